@@ -22,19 +22,55 @@
 
 package ast
 
+import "fmt"
+
+type ClassObject struct {
+	Name       string
+	Superclass *ClassObject
+	Methods    map[string]Function
+}
+
+func (c *ClassObject) CreateInstance() *ClassInstance {
+	return &ClassInstance{c, make(map[string]Literal)}
+}
+
+func (c *ClassObject) FindMethod(name string) (Function, bool) {
+	if m, ok := c.Methods[name]; ok {
+		return m, true
+	}
+
+	if c.Superclass != nil {
+		return c.Superclass.FindMethod(name)
+	}
+
+	return Function{}, false
+}
+
+func (c *ClassObject) String() string {
+	return c.Name
+}
+
 type ClassInstance struct {
-	ClassStmt
+	Class  *ClassObject
 	Fields map[string]Literal
 }
 
-func (c ClassInstance) Get(t Token) Literal {
-	return c.Fields[t.Lexeme]
+func (c *ClassInstance) Get(t Token) (Literal, error) {
+	if l, ok := c.Fields[t.Lexeme]; ok {
+		return l, nil
+	}
+
+	if m, ok := c.Class.FindMethod(t.Lexeme); ok {
+		return Literal{Value: m.Bind(c)}, nil
+	}
+
+	return Literal{}, newError(t.Span(), "undefined property '%s'", t.Lexeme)
 }
 
-func (c ClassInstance) Set(t Token, l Literal) {
+func (c *ClassInstance) Set(t Token, l Literal) {
 	c.Fields[t.Lexeme] = l
 }
 
-func (c ClassInstance) String() string {
-	return c.Name.Lexeme
+func (c *ClassInstance) String() string {
+	return fmt.Sprintf("%s instance", c.Class.Name)
 }
@@ -0,0 +1,111 @@
+//  MIT License
+//
+//  Copyright (c) 2019 Marco Pacini
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy
+//  of this software and associated documentation files (the "Software"), to deal
+//  in the Software without restriction, including without limitation the rights
+//  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//  copies of the Software, and to permit persons to whom the Software is
+//  furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all
+//  copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//  SOFTWARE.
+
+package ast
+
+// Debugger pauses a running program. Before is called ahead of each
+// statement, with the interpreter stopped at it; an error stops the program.
+type Debugger interface {
+	Before(i *Interpreter, stmt Stmt) error
+}
+
+func (i *Interpreter) execute(stmt Stmt) error {
+	if i.Debugger != nil {
+		if err := i.Debugger.Before(i, stmt); err != nil {
+			return err
+		}
+	}
+
+	return stmt.Accept(i)
+}
+
+// Calls returns the functions being run, the innermost first, each with the
+// span of its call.
+func (i *Interpreter) Calls() []Frame {
+	calls := make([]Frame, len(i.calls))
+	for j, frame := range i.calls {
+		calls[len(calls)-1-j] = frame
+	}
+
+	return calls
+}
+
+// Depth returns the number of calls being run.
+func (i *Interpreter) Depth() int {
+	return len(i.calls)
+}
+
+// File returns the path of the file being run: the program, or a module it
+// is importing.
+func (i *Interpreter) File() string {
+	if i.file != "" {
+		return i.file
+	}
+
+	return i.Path
+}
+
+// Eval evaluates the expression in source in the current environment.
+// Variables are looked up by name through the enclosing scopes, so that the
+// expression can use whatever is visible where the program is paused.
+func (i *Interpreter) Eval(source string) (Literal, error) {
+	scanner := Scanner{Text: source}
+	tokens, err := scanner.Scan()
+	if err != nil {
+		return Literal{}, err
+	}
+
+	parser := Parser{Tokens: tokens}
+	expr, err := parser.ParseExpression()
+	if err != nil {
+		return Literal{}, err
+	}
+
+	// with no distances every lookup starts from the innermost scope
+	locals, literal := i.Locals, i.Literal
+	defer func() {
+		i.Locals, i.Literal = locals, literal
+	}()
+
+	i.Locals = make(map[int]int)
+
+	return i.Evaluate(expr)
+}
+
+// Scopes returns the variables of the environments from the innermost one up
+// to, and including, the global one.
+func (i *Interpreter) Scopes() []map[string]Literal {
+	var scopes []map[string]Literal
+
+	for e := i.Environment; e != nil && e != i.Builtins; e = e.Parent {
+		scope := make(map[string]Literal)
+		for name, value := range e.Scope {
+			if l, ok := value.(Literal); ok {
+				scope[name] = l
+			}
+		}
+
+		scopes = append(scopes, scope)
+	}
+
+	return scopes
+}
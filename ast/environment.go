@@ -22,8 +22,6 @@
 
 package ast
 
-import "fmt"
-
 type Environment struct {
 	Parent *Environment
 	Scope  map[string]interface{}
@@ -33,17 +31,23 @@ func NewEnvironment(parent *Environment) *Environment {
 	return &Environment{parent, make(map[string]interface{})}
 }
 
-func (e *Environment) Assign(variable Variable, expr Expr) error {
-	if _, ok := e.Scope[variable.Lexeme]; ok {
-		e.Scope[variable.Lexeme] = expr
+func (e *Environment) Assign(variable Variable, expr Expr, distance int) error {
+	local := e
+
+	for i := 0; i < distance; i++ {
+		local = local.Parent
+	}
+
+	if _, ok := local.Scope[variable.Lexeme]; ok {
+		local.Scope[variable.Lexeme] = expr
 		return nil
 	}
 
-	if e.Parent != nil {
-		return e.Parent.Assign(variable, expr)
+	if local.Parent != nil {
+		return local.Parent.Assign(variable, expr, 0)
 	}
 
-	return fmt.Errorf("error at line %d: undefined variable %v", variable.Line, variable.Lexeme)
+	return newError(variable.Token.Span(), "undefined variable %v", variable.Lexeme)
 }
 
 func (e Environment) Contains(variable Variable) bool {
@@ -78,9 +82,9 @@ func (e Environment) Get(variable Variable, distance int) (interface{}, error) {
 		return local.Parent.Get(variable, 0)
 	}
 
-	return nil, fmt.Errorf("error at line %d: undefined variable %v", variable.Line, variable.Lexeme)
+	return nil, newError(variable.Token.Span(), "undefined variable %v", variable.Lexeme)
 }
 
 func (e *Environment) Set(name string, callable Callable) {
-	e.Scope[name] = Literal{callable}
+	e.Scope[name] = Literal{Value: callable}
 }
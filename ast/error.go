@@ -0,0 +1,229 @@
+//  MIT License
+//
+//  Copyright (c) 2019 Marco Pacini
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy
+//  of this software and associated documentation files (the "Software"), to deal
+//  in the Software without restriction, including without limitation the rights
+//  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//  copies of the Software, and to permit persons to whom the Software is
+//  furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all
+//  copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//  SOFTWARE.
+
+package ast
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Span is a range of source code: Line and Column locate its first character,
+// Offset and Length are measured in bytes from the start of the source.
+type Span struct {
+	Line   int
+	Column int
+	Offset int
+	Length int
+}
+
+// To returns the span going from the start of s to the end of other.
+func (s Span) To(other Span) Span {
+	return Span{s.Line, s.Column, s.Offset, other.Offset + other.Length - s.Offset}
+}
+
+// Error is an error found while scanning, parsing, resolving or running a
+// script, located at the span of the offending code.
+type Error struct {
+	Span
+	Message string
+}
+
+func newError(span Span, format string, a ...interface{}) Error {
+	return Error{span, fmt.Sprintf(format, a...)}
+}
+
+func (e Error) Error() string {
+	return fmt.Sprintf("error at line %d, column %d: %s", e.Line, e.Column, e.Message)
+}
+
+// Render returns the error message followed by the source line it refers to,
+// with the offending code underlined by carets.
+func (e Error) Render(source string) string {
+	return e.Error() + excerpt(e.Span, source)
+}
+
+// excerpt returns the source line of span, with the code in span underlined
+// by carets, or nothing if span is not in source.
+func excerpt(span Span, source string) string {
+	if span.Offset < 0 || span.Offset > len(source) {
+		return ""
+	}
+
+	start := strings.LastIndexByte(source[:span.Offset], '\n') + 1
+
+	end := strings.IndexByte(source[span.Offset:], '\n')
+	if end < 0 {
+		end = len(source)
+	} else {
+		end += span.Offset
+	}
+
+	line := strings.TrimRight(source[start:end], "\r")
+
+	// keep tabs, so that the carets line up with the code above them
+	padding := strings.Map(func(r rune) rune {
+		if r == '\t' {
+			return r
+		}
+
+		return ' '
+	}, source[start:span.Offset])
+
+	length := span.Length
+	if span.Offset+length > end {
+		length = end - span.Offset
+	}
+
+	width := utf8.RuneCountInString(source[span.Offset : span.Offset+length])
+	if width < 1 {
+		width = 1
+	}
+
+	gutter := fmt.Sprintf("%d", span.Line)
+
+	return fmt.Sprintf("\n %s | %s\n %s | %s%s",
+		gutter, line,
+		strings.Repeat(" ", len(gutter)), padding, strings.Repeat("^", width))
+}
+
+// Warning is a likely mistake in a valid program, reported by Lint.
+type Warning struct {
+	Span
+	Message string
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("warning at line %d, column %d: %s", w.Line, w.Column, w.Message)
+}
+
+// Render returns the warning underlining its code, as Error.Render does.
+func (w Warning) Render(source string) string {
+	return w.String() + excerpt(w.Span, source)
+}
+
+// Frame is a call on the stack of a RuntimeError: the function called, the
+// span of the call and the module it is in, if not the program itself.
+type Frame struct {
+	Function string
+	Span
+	File string
+}
+
+// RuntimeError is an error raised while running a script. Its trace lists the
+// calls that were active when it was raised, the innermost first. File is the
+// path of the imported module the error is in, empty for the program itself:
+// its span refers to the source of that file.
+type RuntimeError struct {
+	Span
+	Message string
+	Trace   []Frame
+	File    string
+}
+
+func (e RuntimeError) Error() string {
+	if e.File == "" {
+		return Error{e.Span, e.Message}.Error()
+	}
+
+	return fmt.Sprintf("error in %s at line %d, column %d: %s", e.File, e.Line, e.Column, e.Message)
+}
+
+// Render returns the error rendered as Error.Render does, followed by the
+// stack trace. Source is the text of the file of the error. Consecutive
+// identical frames, as in deep recursion, are shown once.
+func (e RuntimeError) Render(source string) string {
+	var b strings.Builder
+
+	b.WriteString(e.Error() + excerpt(e.Span, source))
+
+	for j := 0; j < len(e.Trace); {
+		frame := e.Trace[j]
+
+		n := 1
+		for j+n < len(e.Trace) && e.Trace[j+n] == frame {
+			n++
+		}
+
+		fmt.Fprintf(&b, "\n  in %s, called at line %d", frame.Function, frame.Line)
+		if frame.File != "" {
+			fmt.Fprintf(&b, " of %s", frame.File)
+		}
+		if n > 1 {
+			fmt.Fprintf(&b, " (%d times)", n)
+		}
+
+		j += n
+	}
+
+	return b.String()
+}
+
+// ErrorList collects every Error reported in a single pass, so that they can
+// be shown all together instead of stopping at the first one.
+type ErrorList []Error
+
+func (l ErrorList) Error() string {
+	messages := make([]string, len(l))
+	for i, e := range l {
+		messages[i] = e.Error()
+	}
+
+	return strings.Join(messages, "\n")
+}
+
+func (l ErrorList) Render(source string) string {
+	messages := make([]string, len(l))
+	for i, e := range l {
+		messages[i] = e.Render(source)
+	}
+
+	return strings.Join(messages, "\n")
+}
+
+// ModuleError reports the errors found scanning, parsing or resolving the
+// module imported from File. Their spans refer to the source of that file.
+type ModuleError struct {
+	File   string
+	Errors ErrorList
+}
+
+func (e ModuleError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		messages[i] = fmt.Sprintf("error in %s at line %d, column %d: %s", e.File, err.Line, err.Column, err.Message)
+	}
+
+	return strings.Join(messages, "\n")
+}
+
+// Render returns the errors rendered as ErrorList.Render does, source being
+// the text of the module.
+func (e ModuleError) Render(source string) string {
+	messages := strings.Split(e.Error(), "\n")
+	for i, err := range e.Errors {
+		messages[i] += excerpt(err.Span, source)
+	}
+
+	return strings.Join(messages, "\n")
+}
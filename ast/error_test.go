@@ -0,0 +1,123 @@
+//  MIT License
+//
+//  Copyright (c) 2019 Marco Pacini
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy
+//  of this software and associated documentation files (the "Software"), to deal
+//  in the Software without restriction, including without limitation the rights
+//  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//  copies of the Software, and to permit persons to whom the Software is
+//  furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all
+//  copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//  SOFTWARE.
+
+package ast
+
+import "testing"
+
+func TestError_Render(t *testing.T) {
+	table := []struct {
+		source string
+		err    Error
+		out    string
+	}{
+		{
+			"var a = ;",
+			Error{Span{1, 9, 8, 1}, "unknown token ';'"},
+			"error at line 1, column 9: unknown token ';'\n 1 | var a = ;\n   |         ^",
+		},
+		{
+			"var a = 1;\n\tprint a + \"b\";\n",
+			Error{Span{2, 10, 20, 1}, "invalid operands for binary +: float64, string"},
+			"error at line 2, column 10: invalid operands for binary +: float64, string\n 2 | \tprint a + \"b\";\n   | \t        ^",
+		},
+		{
+			"f(1,\n  2);",
+			Error{Span{1, 1, 0, 10}, "expected 1 arguments but got 2"},
+			"error at line 1, column 1: expected 1 arguments but got 2\n 1 | f(1,\n   | ^^^^",
+		},
+	}
+
+	for _, test := range table {
+		t.Run(test.source, func(t *testing.T) {
+			if out := test.err.Render(test.source); out != test.out {
+				t.Errorf("want %q, got %q", test.out, out)
+			}
+		})
+	}
+}
+
+func TestRuntimeError_Render(t *testing.T) {
+	source := "fun f(n) {\n  if (n == 0) return nil + 1;\n  return f(n - 1);\n}\nf(2);"
+
+	s := Scanner{source}
+	tokens, err := s.Scan()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := Parser{Tokens: tokens}
+	stmts, err := p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = NewInterpreter().Run(stmts)
+
+	e, ok := err.(RuntimeError)
+	if !ok {
+		t.Fatalf("want RuntimeError, got %T: %v", err, err)
+	}
+
+	want := "error at line 2, column 26: invalid operands for binary +: <nil>, float64\n" +
+		" 2 |   if (n == 0) return nil + 1;\n" +
+		"   |                          ^\n" +
+		"  in f, called at line 3 (2 times)\n" +
+		"  in f, called at line 5"
+
+	if out := e.Render(source); out != want {
+		t.Errorf("want %q, got %q", want, out)
+	}
+}
+
+func TestRuntimeError_StackOverflow(t *testing.T) {
+	source := "fun f(n) {\n  return f(n + 1);\n}\nf(0);"
+
+	s := Scanner{source}
+	tokens, err := s.Scan()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := Parser{Tokens: tokens}
+	stmts, err := p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = NewInterpreter().Run(stmts)
+
+	e, ok := err.(RuntimeError)
+	if !ok {
+		t.Fatalf("want RuntimeError, got %T: %v", err, err)
+	}
+
+	want := "error at line 2, column 10: stack overflow\n" +
+		" 2 |   return f(n + 1);\n" +
+		"   |          ^^^^^^^^\n" +
+		"  in f, called at line 2 (10000 times)\n" +
+		"  in f, called at line 4"
+
+	if out := e.Render(source); out != want {
+		t.Errorf("want %q, got %q", want, out)
+	}
+}
@@ -0,0 +1,79 @@
+//  MIT License
+//
+//  Copyright (c) 2019 Marco Pacini
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy
+//  of this software and associated documentation files (the "Software"), to deal
+//  in the Software without restriction, including without limitation the rights
+//  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//  copies of the Software, and to permit persons to whom the Software is
+//  furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all
+//  copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//  SOFTWARE.
+
+package ast
+
+// ErrorObject is the value a catch clause receives for a runtime error.
+type ErrorObject struct {
+	Message string
+	Line    int
+}
+
+func (e *ErrorObject) Get(t Token) (Literal, error) {
+	switch t.Lexeme {
+	case "message":
+		return Literal{Value: e.Message}, nil
+	case "line":
+		return Literal{Value: float64(e.Line)}, nil
+	}
+
+	return Literal{}, newError(t.Span(), "undefined property '%s' of error", t.Lexeme)
+}
+
+func (e *ErrorObject) String() string {
+	return "<error: " + e.Message + ">"
+}
+
+// thrown carries the value of a throw statement up to the innermost catch
+// clause. If there is none, it is reported as its RuntimeError.
+type thrown struct {
+	Value Literal
+	RuntimeError
+}
+
+func newThrown(value Literal, span Span, file string) thrown {
+	message := "uncaught exception: " + value.String()
+
+	// a rethrown error keeps its own message
+	if e, ok := value.Value.(*ErrorObject); ok {
+		message = e.Message
+	}
+
+	return thrown{value, RuntimeError{span, message, nil, file}}
+}
+
+// caught returns the value a catch clause binds for err, if err can be
+// caught at all: the signals of return, break and continue cannot.
+func caught(err error) (Literal, bool) {
+	switch e := err.(type) {
+	case thrown:
+		return e.Value, true
+	case RuntimeError:
+		return Literal{Value: &ErrorObject{e.Message, e.Line}}, true
+	case Error:
+		return Literal{Value: &ErrorObject{e.Message, e.Line}}, true
+	case ModuleError:
+		return Literal{Value: &ErrorObject{e.Errors[0].Message, e.Errors[0].Line}}, true
+	}
+
+	return Literal{}, false
+}
@@ -25,6 +25,7 @@ package ast
 import (
 	"fmt"
 	"math"
+	"strconv"
 )
 
 type Expr interface {
@@ -37,17 +38,70 @@ type ExprVisitor interface {
 	visitCall(Call) error
 	visitGet(Get) error
 	visitGrouping(Grouping) error
+	visitIndex(Index) error
+	visitIndexSet(IndexSet) error
+	visitLambda(Lambda) error
+	visitListExpr(ListExpr) error
 	visitLiteral(Literal) error
 	visitLogical(Logical) error
+	visitMapExpr(MapExpr) error
 	visitSet(Set) error
+	visitSlice(Slice) error
+	visitSuperExpr(SuperExpr) error
+	visitThisExpr(ThisExpr) error
 	visitUnary(Unary) error
 	visitVariable(Variable) error
 }
 
+// ExprSpan returns the span of expr.
+func ExprSpan(expr Expr) Span {
+	switch e := expr.(type) {
+	case Assign:
+		return e.Span
+	case Binary:
+		return e.Span
+	case Call:
+		return e.Span
+	case Get:
+		return e.Span
+	case Grouping:
+		return e.Span
+	case Index:
+		return e.Span
+	case IndexSet:
+		return e.Span
+	case Lambda:
+		return e.Span
+	case ListExpr:
+		return e.Span
+	case Literal:
+		return e.Span
+	case Logical:
+		return e.Span
+	case MapExpr:
+		return e.Span
+	case Set:
+		return e.Span
+	case Slice:
+		return e.Span
+	case SuperExpr:
+		return e.Span
+	case ThisExpr:
+		return e.Span
+	case Unary:
+		return e.Span
+	case Variable:
+		return e.Span
+	}
+
+	return Span{}
+}
+
 type Assign struct {
 	Variable
 	Token
 	Expr
+	Span Span
 }
 
 func (a Assign) Accept(visitor ExprVisitor) error {
@@ -58,6 +112,7 @@ type Binary struct {
 	Left     Expr
 	Operator Token
 	Right    Expr
+	Span     Span
 }
 
 func (b Binary) Accept(visitor ExprVisitor) error {
@@ -67,6 +122,7 @@ func (b Binary) Accept(visitor ExprVisitor) error {
 type Call struct {
 	Callee    Expr
 	Arguments []Expr
+	Span      Span
 }
 
 func (c Call) Accept(visitor ExprVisitor) error {
@@ -76,6 +132,7 @@ func (c Call) Accept(visitor ExprVisitor) error {
 type Get struct {
 	Name   Token
 	Object Expr
+	Span   Span
 }
 
 func (g Get) Accept(visitor ExprVisitor) error {
@@ -84,14 +141,58 @@ func (g Get) Accept(visitor ExprVisitor) error {
 
 type Grouping struct {
 	Expr
+	Span Span
 }
 
 func (g Grouping) Accept(visitor ExprVisitor) error {
 	return visitor.visitGrouping(g)
 }
 
+type Index struct {
+	Object  Expr
+	Bracket Token
+	Index   Expr
+	Span    Span
+}
+
+func (i Index) Accept(visitor ExprVisitor) error {
+	return visitor.visitIndex(i)
+}
+
+type IndexSet struct {
+	Object  Expr
+	Bracket Token
+	Index   Expr
+	Value   Expr
+	Span    Span
+}
+
+func (i IndexSet) Accept(visitor ExprVisitor) error {
+	return visitor.visitIndexSet(i)
+}
+
+// Lambda is an anonymous function used as an expression.
+type Lambda struct {
+	Function Function
+	Span     Span
+}
+
+func (l Lambda) Accept(visitor ExprVisitor) error {
+	return visitor.visitLambda(l)
+}
+
+type ListExpr struct {
+	Elements []Expr
+	Span     Span
+}
+
+func (l ListExpr) Accept(visitor ExprVisitor) error {
+	return visitor.visitListExpr(l)
+}
+
 type Literal struct {
 	Value interface{}
+	Span  Span
 }
 
 func (l Literal) Bool() bool {
@@ -112,11 +213,23 @@ func (l Literal) String() string {
 	}
 
 	if f, ok := l.Value.(float64); ok {
-		if f == math.Trunc(f) {
-			return fmt.Sprintf("%d", int64(f))
+		switch {
+		case math.IsNaN(f):
+			return "nan"
+		case math.IsInf(f, 1):
+			return "inf"
+		case math.IsInf(f, -1):
+			return "-inf"
+		case f == 0 && math.Signbit(f):
+			return "-0"
+		}
+
+		// integers are exact up to 2^53, larger ones print with an exponent
+		if f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+			return strconv.FormatInt(int64(f), 10)
 		}
 
-		return fmt.Sprintf("%f", f)
+		return strconv.FormatFloat(f, 'g', -1, 64)
 	}
 
 	if b, ok := l.Value.(bool); ok {
@@ -138,25 +251,72 @@ type Logical struct {
 	Left     Expr
 	Operator Token
 	Right    Expr
+	Span     Span
 }
 
 func (l Logical) Accept(visitor ExprVisitor) error {
 	return visitor.visitLogical(l)
 }
 
+// MapExpr is a map literal. Keys[j] is the key of Values[j].
+type MapExpr struct {
+	Keys   []Expr
+	Values []Expr
+	Span   Span
+}
+
+func (m MapExpr) Accept(visitor ExprVisitor) error {
+	return visitor.visitMapExpr(m)
+}
+
 type Set struct {
 	Object Expr
 	Name   Token
 	Value  Expr
+	Span   Span
 }
 
 func (s Set) Accept(visitor ExprVisitor) error {
 	return visitor.visitSet(s)
 }
 
+// Slice is a range of a list, as in xs[start:end], where both bounds can be
+// omitted.
+type Slice struct {
+	Object  Expr
+	Bracket Token
+	Start   Expr
+	End     Expr
+	Span    Span
+}
+
+func (s Slice) Accept(visitor ExprVisitor) error {
+	return visitor.visitSlice(s)
+}
+
+type SuperExpr struct {
+	Keyword Token
+	Method  Token
+	Span    Span
+}
+
+func (s SuperExpr) Accept(visitor ExprVisitor) error {
+	return visitor.visitSuperExpr(s)
+}
+
+type ThisExpr struct {
+	Keyword Token
+	Span    Span
+}
+
+func (t ThisExpr) Accept(visitor ExprVisitor) error {
+	return visitor.visitThisExpr(t)
+}
+
 type Unary struct {
 	Operator Token
 	Right    Expr
+	Span     Span
 }
 
 func (u Unary) Accept(visitor ExprVisitor) error {
@@ -165,6 +325,7 @@ func (u Unary) Accept(visitor ExprVisitor) error {
 
 type Variable struct {
 	Token
+	Span Span
 }
 
 func (v Variable) Accept(visitor ExprVisitor) error {
@@ -23,9 +23,12 @@
 package ast
 
 import (
+	"fmt"
 	"time"
 )
 
+// Callable is implemented by every value that can be called. A negative
+// arity means that any number of arguments is accepted.
 type Callable interface {
 	Arity() int
 	Call(interpreter *Interpreter, arguments []Expr) (Literal, error)
@@ -35,8 +38,40 @@ func (f Function) Arity() int {
 	return len(f.Arguments)
 }
 
+func (f Function) Bind(instance *ClassInstance) Function {
+	f.Closure = NewEnvironment(f.Closure)
+	f.Closure.Declare(Variable{Token: Token{TokenType: This, Lexeme: "this", Line: f.Name.Line}}, Literal{Value: instance})
+
+	return f
+}
+
+// Anonymous reports whether f is a lambda, whose Name is its fun keyword.
+func (f Function) Anonymous() bool {
+	return f.Name.TokenType == Fun
+}
+
+func (f Function) name() string {
+	if f.Anonymous() {
+		return "anonymous"
+	}
+
+	return f.Name.Lexeme
+}
+
+func (f Function) String() string {
+	return fmt.Sprintf("<fn %s>", f.name())
+}
+
 func (f Function) Call(i *Interpreter, arguments []Expr) (Literal, error) {
+	previous, locals, file := i.Environment, i.Locals, i.file
+	defer func() {
+		i.Environment, i.Locals, i.file = previous, locals, file
+	}()
+
+	// the body is resolved against the program where the function is declared
 	i.Environment = NewEnvironment(f.Closure)
+	i.Locals = f.Locals
+	i.file = f.File
 
 	for j, argument := range arguments {
 		expr, err := i.Evaluate(argument)
@@ -44,33 +79,55 @@ func (f Function) Call(i *Interpreter, arguments []Expr) (Literal, error) {
 			return Literal{}, err
 		}
 
-		if err := i.Environment.Declare(Variable{f.Arguments[j]}, expr); err != nil {
+		if err := i.Environment.Declare(Variable{Token: f.Arguments[j]}, expr); err != nil {
 			return Literal{}, err
 		}
 	}
 
 	for _, stmt := range f.Body {
-		if err := stmt.Accept(i); err != nil {
+		if err := i.execute(stmt); err != nil {
 			if r, ok := err.(ReturnValue); ok {
-				i.Environment = i.Environment.Parent
+				if f.Initializer {
+					return f.this(), nil
+				}
+
 				return r.Literal, nil
 			}
 
-			return Literal{}, err
+			return Literal{}, locate(err, f.File)
 		}
 	}
 
-	i.Environment = i.Environment.Parent
+	if f.Initializer {
+		return f.this(), nil
+	}
 
 	return Literal{}, nil // void
 }
 
-func (c ClassStmt) Arity() int {
+func (f Function) this() Literal {
+	this, _ := f.Closure.Get(Variable{Token: Token{TokenType: This, Lexeme: "this", Line: f.Name.Line}}, 0)
+	return this.(Literal)
+}
+
+func (c *ClassObject) Arity() int {
+	if initializer, ok := c.FindMethod("init"); ok {
+		return initializer.Arity()
+	}
+
 	return 0
 }
 
-func (c ClassStmt) Call(i *Interpreter, arguments []Expr) (Literal, error) {
-	return c.CreateInstance(), nil
+func (c *ClassObject) Call(i *Interpreter, arguments []Expr) (Literal, error) {
+	instance := c.CreateInstance()
+
+	if initializer, ok := c.FindMethod("init"); ok {
+		if _, err := initializer.Bind(instance).Call(i, arguments); err != nil {
+			return Literal{}, err
+		}
+	}
+
+	return Literal{Value: instance}, nil
 }
 
 type Clock struct{}
@@ -80,5 +137,37 @@ func (c Clock) Arity() int {
 }
 
 func (c Clock) Call(interpreter *Interpreter, arguments []Expr) (Literal, error) {
-	return Literal{time.Now().Unix()}, nil
+	return Literal{Value: time.Now().Unix()}, nil
+}
+
+func (c Clock) String() string {
+	return "<native fn clock>"
+}
+
+// Native is a function implemented in Go.
+type Native struct {
+	name  string
+	arity int
+	fn    func(i *Interpreter, arguments []Literal) (Literal, error)
+}
+
+func NewNative(name string, arity int, fn func(i *Interpreter, arguments []Literal) (Literal, error)) Native {
+	return Native{name, arity, fn}
+}
+
+func (n Native) Arity() int {
+	return n.arity
+}
+
+func (n Native) Call(i *Interpreter, arguments []Expr) (Literal, error) {
+	literals := make([]Literal, len(arguments))
+	for j, argument := range arguments {
+		literals[j], _ = argument.(Literal)
+	}
+
+	return n.fn(i, literals)
+}
+
+func (n Native) String() string {
+	return fmt.Sprintf("<native fn %s>", n.name)
 }
@@ -0,0 +1,262 @@
+//  MIT License
+//
+//  Copyright (c) 2019 Marco Pacini
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy
+//  of this software and associated documentation files (the "Software"), to deal
+//  in the Software without restriction, including without limitation the rights
+//  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//  copies of the Software, and to permit persons to whom the Software is
+//  furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all
+//  copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//  SOFTWARE.
+
+package ast_test
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/marcopacini/go-lox/ast"
+	"github.com/marcopacini/go-lox/vm"
+)
+
+// expectation is an error expected at a line of a test program.
+type expectation struct {
+	line    int
+	message string
+}
+
+var (
+	expectOutput       = regexp.MustCompile(`// expect: ?(.*)$`)
+	expectError        = regexp.MustCompile(`// (?:\[line (\d+)\] )?expect error: (.+)$`)
+	expectRuntimeError = regexp.MustCompile(`// expect runtime error: (.+)$`)
+)
+
+// TestGolden runs the programs in testdata, comparing what they print and the
+// errors they raise with the expectations written in their comments:
+//
+//	print 1 + 2;  // expect: 3
+//	print a;      // expect runtime error: undefined variable a
+//	var a = ;     // expect error: unknown token ';'
+//
+// Errors are expected at the line of the comment, unless it starts with
+// [line N]. A program expecting static errors, found by the scanner, the
+// parser or the resolver, is not run.
+//
+// Every program is run by the interpreter and by the virtual machine, except
+// those listed in vmUnsupported.
+func TestGolden(t *testing.T) {
+	err := filepath.Walk("testdata", func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || filepath.Ext(path) != ".lox" {
+			return err
+		}
+
+		name := filepath.ToSlash(strings.TrimPrefix(path, "testdata"+string(filepath.Separator)))
+
+		for _, b := range backends {
+			b := b
+			t.Run(b.name+"/"+name, func(t *testing.T) {
+				if feature, ok := unsupported(b.skip, name); ok {
+					t.Skipf("%s are not supported", feature)
+				}
+
+				runGolden(t, path, b.run)
+			})
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// backend runs a program accepted by the front end, writing what it prints
+// to stdout.
+type backend func(t *testing.T, stmts []ast.Stmt, path string, stdout io.Writer) error
+
+var backends = []struct {
+	name string
+	run  backend
+	// the programs not run, see unsupported
+	skip map[string]string
+}{
+	{"interpreter", interpret, nil},
+	{"vm", runVM, vmUnsupported},
+}
+
+// vmUnsupported maps the programs, or the directories of programs, using
+// features the virtual machine lacks to those features.
+var vmUnsupported = map[string]string{
+	"import/main.lox":    "modules",
+	"list":               "lists",
+	"map":                "maps",
+	"math":               "the math natives",
+	"string/library.lox": "slices and the string natives",
+	"try/catch.lox":      "exceptions",
+	"try/finally.lox":    "exceptions",
+	"try/uncaught.lox":   "exceptions",
+}
+
+// unsupported returns the feature for which the program name is in skip,
+// itself or by one of its directories.
+func unsupported(skip map[string]string, name string) (string, bool) {
+	for ; name != "."; name = path.Dir(name) {
+		if feature, ok := skip[name]; ok {
+			return feature, true
+		}
+	}
+
+	return "", false
+}
+
+func interpret(t *testing.T, stmts []ast.Stmt, path string, stdout io.Writer) error {
+	i := ast.NewInterpreter()
+	i.Stdout = stdout
+	i.Path, _ = filepath.Abs(path)
+
+	return i.Run(stmts)
+}
+
+func runVM(t *testing.T, stmts []ast.Stmt, path string, stdout io.Writer) error {
+	f, err := vm.Compile(stmts)
+	if err != nil {
+		return err
+	}
+
+	m := vm.New()
+	m.Stdout = stdout
+
+	return m.Interpret(f)
+}
+
+func runGolden(t *testing.T, path string, run backend) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var output []string
+	var errors []expectation
+	var runtimeError *expectation
+
+	for j, line := range strings.Split(string(b), "\n") {
+		if m := expectOutput.FindStringSubmatch(line); m != nil {
+			output = append(output, m[1])
+		} else if m := expectError.FindStringSubmatch(line); m != nil {
+			e := expectation{j + 1, m[2]}
+			if m[1] != "" {
+				e.line, _ = strconv.Atoi(m[1])
+			}
+
+			errors = append(errors, e)
+		} else if m := expectRuntimeError.FindStringSubmatch(line); m != nil {
+			runtimeError = &expectation{j + 1, m[1]}
+		}
+	}
+
+	var found []expectation
+	collect := func(err error) {
+		switch e := err.(type) {
+		case ast.ErrorList:
+			for _, e := range e {
+				found = append(found, expectation{e.Span.Line, e.Message})
+			}
+		case ast.Error:
+			found = append(found, expectation{e.Span.Line, e.Message})
+		case ast.RuntimeError:
+			found = append(found, expectation{e.Span.Line, e.Message})
+		case vm.RuntimeError:
+			found = append(found, expectation{e.Line, e.Message})
+		default:
+			found = append(found, expectation{0, err.Error()})
+		}
+	}
+
+	scanner := ast.Scanner{Text: string(b)}
+	tokens, err := scanner.Scan()
+	if err != nil {
+		collect(err)
+	}
+
+	parser := ast.Parser{Tokens: tokens}
+	stmts, err := parser.Parse()
+	if err != nil {
+		collect(err)
+	}
+
+	if len(found) == 0 {
+		var resolver ast.Resolver
+		if err := resolver.Resolve(stmts); err != nil {
+			collect(err)
+		}
+	}
+
+	if len(found) > 0 || len(errors) > 0 {
+		if !equalExpectations(found, errors) {
+			t.Fatalf("want errors %v, got %v", errors, found)
+		}
+
+		return
+	}
+
+	var stdout bytes.Buffer
+
+	err = run(t, stmts, path, &stdout)
+
+	if want := strings.Join(output, "\n"); strings.TrimSuffix(stdout.String(), "\n") != want {
+		t.Errorf("want output\n%s\ngot\n%s", want, stdout.String())
+	}
+
+	if runtimeError == nil {
+		if err != nil {
+			t.Errorf("unexpected runtime error: %v", err)
+		}
+
+		return
+	}
+
+	if err == nil {
+		t.Fatalf("want runtime error %v, got none", *runtimeError)
+	}
+
+	found = nil
+	collect(err)
+
+	if !equalExpectations(found, []expectation{*runtimeError}) {
+		t.Errorf("want runtime error %v, got %v", *runtimeError, found[0])
+	}
+}
+
+func equalExpectations(a, b []expectation) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for j := range a {
+		if a[j] != b[j] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -23,13 +23,88 @@
 package ast
 
 import (
+	"bufio"
 	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
 )
 
+// DefaultMaxCallDepth is the number of calls an interpreter lets be active at
+// once, unless told otherwise. It is well below the depth exhausting the Go
+// stack.
+const DefaultMaxCallDepth = 10000
+
+// builtinNatives are the natives every interpreter starts with.
+var builtinNatives = [][]Native{listNatives, mapNatives, stringNatives, mathNatives, ioNatives}
+
 type Interpreter struct {
 	Literal
-	Locals map[string]int
+	Locals   map[int]int
+	Builtins *Environment
+	Globals  *Environment
 	*Environment
+	resolver Resolver
+
+	// Stdout, Stderr and Stdin are used by print and by the I/O natives.
+	Stdout io.Writer
+	Stderr io.Writer
+	Stdin  io.Reader
+
+	// Path is the file of the program being run, imports are resolved
+	// relative to its directory.
+	Path      string
+	modules   map[string]*Module
+	importing []string
+	// the module whose code is running, empty for the program itself
+	file string
+
+	// MaxCallDepth is the number of calls that can be active at once, a call
+	// beyond it raises a stack overflow error.
+	MaxCallDepth int
+
+	// Debugger, if set, is told about each statement before it runs.
+	Debugger Debugger
+	// the calls being run, the outermost first
+	calls []Frame
+
+	rand  *rand.Rand
+	input *bufio.Reader
+	// the reader buffered by input
+	inputSource io.Reader
+}
+
+// NewInterpreter returns an interpreter whose global environment is kept
+// across calls to Run. It is nested in the environment of the builtins,
+// which is shared with the imported modules.
+func NewInterpreter() *Interpreter {
+	builtins := NewEnvironment(nil)
+	builtins.Set("clock", Clock{})
+
+	for _, natives := range builtinNatives {
+		for _, n := range natives {
+			builtins.Set(n.name, n)
+		}
+	}
+
+	for name, value := range mathConstants {
+		builtins.Declare(Variable{Token: Token{TokenType: Identifier, Lexeme: name}}, Literal{Value: value})
+	}
+
+	globals := NewEnvironment(builtins)
+
+	return &Interpreter{
+		Locals:       make(map[int]int),
+		Builtins:     builtins,
+		Globals:      globals,
+		Environment:  globals,
+		Stdout:       os.Stdout,
+		Stderr:       os.Stderr,
+		Stdin:        os.Stdin,
+		MaxCallDepth: DefaultMaxCallDepth,
+		modules:      make(map[string]*Module),
+	}
 }
 
 type ReturnValue struct {
@@ -40,27 +115,51 @@ func (r ReturnValue) Error() string {
 	return r.Literal.String()
 }
 
-func (i *Interpreter) Run(stmts []Stmt) error {
-	r := Resolver{}
+// breakSignal and continueSignal unwind the statements of a loop body up to
+// the innermost loop, as ReturnValue does for function bodies.
+type breakSignal struct{}
 
-	if err := r.Resolve(stmts); err != nil {
+func (breakSignal) Error() string {
+	return "break outside of a loop"
+}
+
+type continueSignal struct{}
+
+func (continueSignal) Error() string {
+	return "continue outside of a loop"
+}
+
+func (i *Interpreter) Run(stmts []Stmt) error {
+	if err := i.resolver.Resolve(stmts); err != nil {
 		return err
 	}
 
-	i.Locals = r.Locals
-
-	i.Environment = NewEnvironment(nil)
-	i.Environment.Set("clock", Clock{})
+	i.Locals = i.resolver.Locals
 
 	for _, stmt := range stmts {
-		if err := stmt.Accept(i); err != nil {
-			return err
+		if err := i.execute(stmt); err != nil {
+			// a return outside of a function ends the program
+			if _, ok := err.(ReturnValue); ok {
+				return nil
+			}
+
+			if e, ok := err.(thrown); ok {
+				return e.RuntimeError
+			}
+
+			return locate(err, "")
 		}
 	}
 
 	return nil
 }
 
+// Define declares a builtin variable holding value, visible to the program
+// and to the modules it imports.
+func (i *Interpreter) Define(name string, value interface{}) {
+	i.Builtins.Declare(Variable{Token: Token{TokenType: Identifier, Lexeme: name}}, Literal{Value: value})
+}
+
 func (i *Interpreter) Evaluate(expr Expr) (Literal, error) {
 	err := expr.Accept(i)
 	return i.Literal, err
@@ -72,7 +171,9 @@ func (i *Interpreter) visitAssign(a Assign) error {
 		return err
 	}
 
-	if err := i.Environment.Assign(a.Variable, l); err != nil {
+	distance, _ := i.Locals[localKey(a.Variable.Token)]
+
+	if err := i.Environment.Assign(a.Variable, l, distance); err != nil {
 		return err
 	}
 
@@ -95,124 +196,105 @@ func (i *Interpreter) visitBinary(b Binary) error {
 	}
 
 	invalidOperand := func(left interface{}, right interface{}) error {
-		return fmt.Errorf("error at line %d: invalid operands for binary %s: %T, %T", b.Operator.Line, b.Operator.Lexeme, left, right)
+		return newError(b.Operator.Span(), "invalid operands for binary %s: %T, %T", b.Operator.Lexeme, left, right)
 	}
 
 	switch b.Operator.TokenType {
 	case Plus:
 		{
-			if l, ok := left.Value.(float64); ok {
+			switch l := left.Value.(type) {
+			case float64:
+				// Sum of numbers
 				if r, ok := right.Value.(float64); ok {
-					// Sum of numbers
-					i.Literal = Literal{l + r}
+					i.Literal = Literal{Value: l + r}
+					return nil
 				}
-			} else if l, ok := left.Value.(string); ok {
+			case string:
+				// String concatenation
 				if r, ok := right.Value.(string); ok {
-					// String concatenation
-					i.Literal = Literal{l + r}
-				}
-			} else {
-				// Invalids operands
-				return invalidOperand(left.Value, right.Value)
-			}
-		}
-	case Minus:
-		{
-			if l, ok := left.Value.(float64); ok {
-				if r, ok := right.Value.(float64); ok {
-					i.Literal = Literal{l - r}
+					i.Literal = Literal{Value: l + r}
+					return nil
 				}
-			} else {
-				return invalidOperand(left.Value, right.Value)
-			}
-		}
-	case Star:
-		{
-			if l, ok := left.Value.(float64); ok {
-				if r, ok := right.Value.(float64); ok {
-					i.Literal = Literal{l * r}
+			case *List:
+				// List concatenation
+				if r, ok := right.Value.(*List); ok {
+					elements := append(append([]Literal{}, l.Elements...), r.Elements...)
+					i.Literal = Literal{Value: NewList(elements)}
+					return nil
 				}
-			} else {
-				return invalidOperand(left.Value, right.Value)
-			}
-		}
-	case Slash:
-		{
-			if l, ok := left.Value.(float64); ok {
-				if r, ok := right.Value.(float64); ok {
-					i.Literal = Literal{l / r}
-				}
-			} else {
-				return invalidOperand(left.Value, right.Value)
 			}
+
+			// Invalids operands
+			return invalidOperand(left.Value, right.Value)
 		}
 	case EqualEqual:
 		{
-			i.Literal = Literal{left.Value == right.Value}
+			i.Literal = Literal{Value: isEqual(left.Value, right.Value)}
+			return nil
 		}
 	case NotEqual:
 		{
-			i.Literal = Literal{left.Value != right.Value}
+			i.Literal = Literal{Value: !isEqual(left.Value, right.Value)}
+			return nil
 		}
+	}
+
+	// all the other operators take numbers
+	l, ok := left.Value.(float64)
+	if !ok {
+		return invalidOperand(left.Value, right.Value)
+	}
+
+	r, ok := right.Value.(float64)
+	if !ok {
+		return invalidOperand(left.Value, right.Value)
+	}
+
+	switch b.Operator.TokenType {
+	case Minus:
+		i.Literal = Literal{Value: l - r}
+	case Star:
+		i.Literal = Literal{Value: l * r}
+	case Slash:
+		i.Literal = Literal{Value: l / r}
+	case Percent:
+		i.Literal = Literal{Value: math.Mod(l, r)}
+	case StarStar:
+		i.Literal = Literal{Value: math.Pow(l, r)}
 	case Greater:
-		{
-			if l, ok := left.Value.(float64); ok {
-				if r, ok := right.Value.(float64); ok {
-					i.Literal = Literal{l > r}
-				} else {
-					return invalidOperand(left.Value, right.Value)
-				}
-			}
-		}
+		i.Literal = Literal{Value: l > r}
 	case GreaterEqual:
-		{
-			if l, ok := left.Value.(float64); ok {
-				if r, ok := right.Value.(float64); ok {
-					i.Literal = Literal{l >= r}
-				} else {
-					return invalidOperand(left.Value, right.Value)
-				}
-			}
-		}
+		i.Literal = Literal{Value: l >= r}
 	case Less:
-		{
-			if l, ok := left.Value.(float64); ok {
-				if r, ok := right.Value.(float64); ok {
-					i.Literal = Literal{l < r}
-				} else {
-					return invalidOperand(left.Value, right.Value)
-				}
-			}
-		}
+		i.Literal = Literal{Value: l < r}
 	case LessEqual:
-		{
-			if l, ok := left.Value.(float64); ok {
-				if r, ok := right.Value.(float64); ok {
-					i.Literal = Literal{l <= r}
-				} else {
-					return invalidOperand(left.Value, right.Value)
-				}
-			}
-		}
+		i.Literal = Literal{Value: l <= r}
 	}
 
 	return nil
 }
 
 func (i *Interpreter) visitBlock(b Block) error {
+	previous := i.Environment
+	defer func() {
+		i.Environment = previous
+	}()
+
 	i.Environment = NewEnvironment(i.Environment)
 
 	for _, stmt := range b.Stmts {
-		if err := stmt.Accept(i); err != nil {
+		if err := i.execute(stmt); err != nil {
 			return err
 		}
 	}
 
-	i.Environment = i.Environment.Parent
-
 	return nil
 }
 
+func (i *Interpreter) visitBreakStmt(b BreakStmt) error {
+	return breakSignal{}
+}
+
 func (i *Interpreter) visitCall(c Call) error {
 	callee, err := i.Evaluate(c.Callee)
 	if err != nil {
@@ -229,28 +311,139 @@ func (i *Interpreter) visitCall(c Call) error {
 		arguments = append(arguments, value)
 	}
 
-	if f, ok := callee.Value.(Callable); ok {
-		if f.Arity() != len(arguments) {
-			return fmt.Errorf("expected %d arguments but got %d", f.Arity(), len(arguments))
+	f, ok := callee.Value.(Callable)
+	if !ok {
+		return newError(c.Span, "can only call functions and classes")
+	}
+
+	if f.Arity() >= 0 && f.Arity() != len(arguments) {
+		return newError(c.Span, "expected %d arguments but got %d", f.Arity(), len(arguments))
+	}
+
+	name, traced := callName(f)
+	if traced {
+		if len(i.calls) >= i.MaxCallDepth {
+			return unwind(newError(c.Span, "stack overflow"), f, c.Span, i.file)
 		}
 
-		l, err := f.Call(i, arguments)
-		if err != nil {
+		i.calls = append(i.calls, Frame{name, c.Span, i.file})
+	}
+
+	l, err := f.Call(i, arguments)
+
+	if traced {
+		i.calls = i.calls[:len(i.calls)-1]
+	}
+
+	if err != nil {
+		return unwind(err, f, c.Span, i.file)
+	}
+
+	i.Literal = l
+
+	return nil
+}
+
+// unwind adds the call of f at span, in the given file, to the trace of the
+// error raised by the call.
+func unwind(err error, f Callable, span Span, file string) error {
+	name, ok := callName(f)
+	if !ok {
+		switch err.(type) {
+		case Error, RuntimeError, ModuleError, thrown:
+			// raised by a function called back by the native
 			return err
 		}
 
-		i.Literal = l
+		// errors from native functions have no position
+		return newError(span, "%v", err)
 	}
 
-	return nil
+	switch e := err.(type) {
+	case RuntimeError:
+		e.Trace = append(e.Trace, Frame{name, span, file})
+		return e
+	case thrown:
+		e.Trace = append(e.Trace, Frame{name, span, file})
+		return e
+	case Error:
+		return RuntimeError{e.Span, e.Message, []Frame{{name, span, file}}, file}
+	}
+
+	return err
+}
+
+// locate turns err, raised by the code of file, into a RuntimeError knowing
+// its file. Errors leave the code of a file when a function returns, a module
+// is loaded or the program ends.
+func locate(err error, file string) error {
+	if e, ok := err.(Error); ok {
+		return RuntimeError{e.Span, e.Message, nil, file}
+	}
+
+	return err
+}
+
+// callName returns the name shown in traces for a call of f, reporting
+// whether f is one that runs Lox code.
+func callName(f Callable) (string, bool) {
+	switch f := f.(type) {
+	case Function:
+		return f.name(), true
+	case *ClassObject:
+		return f.Name, true
+	}
+
+	return "", false
 }
 
 func (i *Interpreter) visitClassStmt(c ClassStmt) error {
-	return i.Environment.Declare(Variable{c.Name}, Literal{c})
+	var superclass *ClassObject
+
+	if c.Superclass != nil {
+		l, err := i.Evaluate(*c.Superclass)
+		if err != nil {
+			return err
+		}
+
+		class, ok := l.Value.(*ClassObject)
+		if !ok {
+			return newError(c.Superclass.Span, "superclass must be a class")
+		}
+
+		superclass = class
+	}
+
+	if err := i.Environment.Declare(Variable{Token: c.Name}, Literal{Value: nil}); err != nil {
+		return err
+	}
+
+	closure := i.Environment
+	if superclass != nil {
+		closure = NewEnvironment(closure)
+		if err := closure.Declare(Variable{Token: Token{TokenType: Super, Lexeme: "super", Line: c.Name.Line}}, Literal{Value: superclass}); err != nil {
+			return err
+		}
+	}
+
+	methods := make(map[string]Function)
+	for _, method := range c.Methods {
+		method.Closure = closure
+		method.Locals = i.Locals
+		method.File = i.file
+		method.Initializer = method.Name.Lexeme == "init"
+		methods[method.Name.Lexeme] = method
+	}
+
+	return i.Environment.Assign(Variable{Token: c.Name}, Literal{Value: &ClassObject{c.Name.Lexeme, superclass, methods}}, 0)
+}
+
+func (i *Interpreter) visitContinueStmt(c ContinueStmt) error {
+	return continueSignal{}
 }
 
 func (i *Interpreter) visitDeclaration(d Declaration) error {
-	i.Literal = Literal{nil}
+	i.Literal = Literal{Value: nil}
 
 	if d.Expr != nil {
 		if _, err := i.Evaluate(d.Expr); err != nil {
@@ -258,7 +451,7 @@ func (i *Interpreter) visitDeclaration(d Declaration) error {
 		}
 	}
 
-	if err := i.Environment.Declare(Variable{d.Token}, i.Literal); err != nil {
+	if err := i.Environment.Declare(Variable{Token: d.Token}, i.Literal); err != nil {
 		return err
 	}
 
@@ -277,30 +470,49 @@ func (i *Interpreter) visitForStmt(f ForStmt) error {
 	}
 
 	for true {
-		l, err := i.Evaluate(f.Condition)
-		if err != nil {
-			return err
-		}
+		if f.Condition != nil {
+			l, err := i.Evaluate(f.Condition)
+			if err != nil {
+				return err
+			}
 
-		if !l.Bool() {
-			return nil
+			if !l.Bool() {
+				return nil
+			}
 		}
 
-		if err := f.Body.Accept(i); err != nil {
+		if done, err := i.runLoopBody(f.Body); done || err != nil {
 			return err
 		}
 
-		if err := f.Increment.Accept(i); err != nil {
-			return err
+		if f.Increment != nil {
+			if _, err := i.Evaluate(f.Increment); err != nil {
+				return err
+			}
 		}
 	}
 
 	return nil
 }
 
+// runLoopBody executes the body of a loop, reporting whether a break
+// statement ended the loop.
+func (i *Interpreter) runLoopBody(body Stmt) (bool, error) {
+	switch err := i.execute(body); err.(type) {
+	case breakSignal:
+		return true, nil
+	case continueSignal:
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
 func (i *Interpreter) visitFunction(f Function) error {
 	f.Closure = i.Environment
-	if err := i.Environment.Declare(Variable{f.Name}, Literal{f}); err != nil {
+	f.Locals = i.Locals
+	f.File = i.file
+	if err := i.Environment.Declare(Variable{Token: f.Name}, Literal{Value: f}); err != nil {
 		return err
 	}
 
@@ -314,12 +526,12 @@ func (i *Interpreter) visitIfStmt(s IfStmt) error {
 	}
 
 	if l.Bool() {
-		if err := s.Then.Accept(i); err != nil {
+		if err := i.execute(s.Then); err != nil {
 			return err
 		}
 	} else {
 		if s.Else != nil {
-			if err := s.Else.Accept(i); err != nil {
+			if err := i.execute(s.Else); err != nil {
 				return err
 			}
 		}
@@ -328,13 +540,132 @@ func (i *Interpreter) visitIfStmt(s IfStmt) error {
 	return nil
 }
 
+func (i *Interpreter) visitImportStmt(s ImportStmt) error {
+	from := i.Path
+	if i.file != "" {
+		from = i.file
+	}
+
+	path, err := findModule(s.Path.Literal, from)
+	if err != nil {
+		return newError(s.Path.Span(), "%v", err)
+	}
+
+	m, err := i.load(path)
+	if err != nil {
+		// errors in the module are located there
+		switch err.(type) {
+		case RuntimeError, ModuleError, thrown:
+			return err
+		}
+
+		return newError(s.Path.Span(), "%v", err)
+	}
+
+	name := s.name()
+	if s.Name == nil && !isIdentifier(name) {
+		return newError(s.Path.Span(), "cannot bind module to '%s', use 'import name from'", name)
+	}
+
+	return i.Environment.Declare(Variable{Token: Token{TokenType: Identifier, Lexeme: name}}, Literal{Value: m})
+}
+
+func (i *Interpreter) visitIndex(x Index) error {
+	object, err := i.Evaluate(x.Object)
+	if err != nil {
+		return err
+	}
+
+	subscript, err := i.Evaluate(x.Index)
+	if err != nil {
+		return err
+	}
+
+	switch o := object.Value.(type) {
+	case *List:
+		i.Literal, err = o.Get(x.Bracket, subscript)
+	case *Map:
+		if i.Literal, err = o.Get(subscript); err != nil {
+			err = newError(x.Bracket.Span(), "%v", err)
+		}
+	case string:
+		runes := []rune(o)
+
+		n, err := index(subscript, len(runes))
+		if err != nil {
+			return newError(x.Bracket.Span(), "%v", err)
+		}
+
+		i.Literal = Literal{Value: string(runes[n])}
+	default:
+		err = newError(x.Bracket.Span(), "only lists, maps and strings can be indexed")
+	}
+
+	return err
+}
+
+func (i *Interpreter) visitIndexSet(x IndexSet) error {
+	object, err := i.Evaluate(x.Object)
+	if err != nil {
+		return err
+	}
+
+	subscript, err := i.Evaluate(x.Index)
+	if err != nil {
+		return err
+	}
+
+	value, err := i.Evaluate(x.Value)
+	if err != nil {
+		return err
+	}
+
+	switch o := object.Value.(type) {
+	case *List:
+		err = o.Set(x.Bracket, subscript, Literal{Value: value.Value})
+	case *Map:
+		if err = o.Set(subscript, Literal{Value: value.Value}); err != nil {
+			err = newError(x.Bracket.Span(), "%v", err)
+		}
+	case string:
+		err = newError(x.Bracket.Span(), "strings cannot be modified")
+	default:
+		err = newError(x.Bracket.Span(), "only lists and maps can be indexed")
+	}
+
+	if err != nil {
+		return err
+	}
+
+	i.Literal = value
+
+	return nil
+}
+
+func (i *Interpreter) visitListExpr(l ListExpr) error {
+	elements := make([]Literal, len(l.Elements))
+
+	for j, element := range l.Elements {
+		value, err := i.Evaluate(element)
+		if err != nil {
+			return err
+		}
+
+		elements[j] = Literal{Value: value.Value}
+	}
+
+	i.Literal = Literal{Value: NewList(elements)}
+
+	return nil
+}
+
 func (i *Interpreter) visitPrintStmt(p PrintStmt) error {
 	expr, err := i.Evaluate(p.Expr)
 	if err != nil {
 		return err
 	}
 
-	fmt.Println(expr)
+	fmt.Fprintln(i.Stdout, expr)
 
 	return nil
 }
@@ -342,27 +673,45 @@ func (i *Interpreter) visitPrintStmt(p PrintStmt) error {
 func (i *Interpreter) visitGet(g Get) error {
 	l, err := i.Evaluate(g.Object)
 	if err != nil {
-		return nil
+		return err
 	}
 
-	if obj, ok := l.Value.(ClassInstance); ok {
-		i.Literal = obj.Get(g.Name)
-	} else {
-		return fmt.Errorf("error at line %d: invalid property: %v", g.Name.Line, g.Name.Lexeme)
+	switch obj := l.Value.(type) {
+	case *ClassInstance:
+		i.Literal, err = obj.Get(g.Name)
+	case *Module:
+		i.Literal, err = obj.Get(g.Name)
+	case *ErrorObject:
+		i.Literal, err = obj.Get(g.Name)
+	default:
+		err = newError(g.Name.Span(), "invalid property: %v", g.Name.Lexeme)
 	}
 
-	return nil
+	return err
 }
 
 func (i *Interpreter) visitGrouping(g Grouping) error {
 	return g.Expr.Accept(i)
 }
 
+func (i *Interpreter) visitLambda(l Lambda) error {
+	f := l.Function
+	f.Closure = i.Environment
+	f.Locals = i.Locals
+	f.File = i.file
+
+	i.Literal = Literal{Value: f}
+
+	return nil
+}
+
 func (i *Interpreter) visitLiteral(l Literal) error {
 	i.Literal = l
 	return nil
 }
 
+// visitLogical evaluates to the operand that decides the result: the left one,
+// if it is enough, otherwise the right one.
 func (i *Interpreter) visitLogical(l Logical) error {
 	left, err := i.Evaluate(l.Left)
 	if err != nil {
@@ -371,41 +720,47 @@ func (i *Interpreter) visitLogical(l Logical) error {
 
 	switch l.Operator.TokenType {
 	case Or:
-		{
-			if !left.Bool() {
-				right, err := i.Evaluate(l.Right)
-				if err != nil {
-					return err
-				}
+		if left.Bool() {
+			return nil
+		}
+	case And:
+		if !left.Bool() {
+			return nil
+		}
+	}
 
-				i.Literal = Literal{right.Bool()}
-			} else {
-				i.Literal = Literal{true}
-			}
+	return l.Right.Accept(i)
+}
+
+func (i *Interpreter) visitMapExpr(m MapExpr) error {
+	result := NewMap()
 
-			break
+	for j := range m.Keys {
+		k, err := i.Evaluate(m.Keys[j])
+		if err != nil {
+			return err
 		}
-	case And:
-		{
-			if left.Bool() {
-				right, err := i.Evaluate(l.Right)
-				if err != nil {
-					return err
-				}
 
-				i.Literal = Literal{right.Bool()}
-			} else {
-				i.Literal = Literal{false}
-			}
+		value, err := i.Evaluate(m.Values[j])
+		if err != nil {
+			return err
+		}
 
-			break
+		if err := result.Set(k, Literal{Value: value.Value}); err != nil {
+			return newError(m.Span, "%v", err)
 		}
 	}
 
+	i.Literal = Literal{Value: result}
+
 	return nil
 }
 
 func (i *Interpreter) visitReturnStmt(r ReturnStmt) error {
+	if r.Expr == nil {
+		return ReturnValue{Literal{Value: nil}}
+	}
+
 	if err := r.Expr.Accept(i); err != nil {
 		return err
 	} else {
@@ -416,39 +771,134 @@ func (i *Interpreter) visitReturnStmt(r ReturnStmt) error {
 func (i *Interpreter) visitSet(s Set) error {
 	l, err := i.Evaluate(s.Object)
 	if err != nil {
-		return nil
+		return err
 	}
 
-	if obj, ok := l.Value.(ClassInstance); ok {
+	switch obj := l.Value.(type) {
+	case *ClassInstance:
 		l, err := i.Evaluate(s.Value)
 		if err != nil {
 			return err
 		}
 
 		obj.Set(s.Name, l)
+	case *Module:
+		l, err := i.Evaluate(s.Value)
+		if err != nil {
+			return err
+		}
+
+		return obj.Set(s.Name, l)
+	default:
+		return newError(s.Name.Span(), "only instances have fields")
 	}
 
 	return nil
 }
 
+func (i *Interpreter) visitSlice(s Slice) error {
+	object, err := i.Evaluate(s.Object)
+	if err != nil {
+		return err
+	}
+
+	var start, end Literal
+
+	if s.Start != nil {
+		if start, err = i.Evaluate(s.Start); err != nil {
+			return err
+		}
+	}
+
+	if s.End != nil {
+		if end, err = i.Evaluate(s.End); err != nil {
+			return err
+		}
+	}
+
+	switch o := object.Value.(type) {
+	case *List:
+		slice, err := o.Slice(s.Bracket, start, end)
+		if err != nil {
+			return err
+		}
+
+		i.Literal = Literal{Value: slice}
+	case string:
+		runes := []rune(o)
+
+		from, to, err := bounds(start, end, len(runes))
+		if err != nil {
+			return newError(s.Bracket.Span(), "%v", err)
+		}
+
+		i.Literal = Literal{Value: string(runes[from:to])}
+	default:
+		return newError(s.Bracket.Span(), "only lists and strings can be sliced")
+	}
+
+	return nil
+}
+
+func (i *Interpreter) visitSuperExpr(s SuperExpr) error {
+	distance, _ := i.Locals[localKey(s.Keyword)]
+
+	e, err := i.Environment.Get(Variable{Token: s.Keyword}, distance)
+	if err != nil {
+		return err
+	}
+
+	superclass, _ := e.(Literal).Value.(*ClassObject)
+
+	// 'this' is always declared one environment nearer than 'super'
+	this, err := i.Environment.Get(Variable{Token: Token{TokenType: This, Lexeme: "this", Line: s.Keyword.Line}}, distance-1)
+	if err != nil {
+		return err
+	}
+
+	instance, _ := this.(Literal).Value.(*ClassInstance)
+
+	method, ok := superclass.FindMethod(s.Method.Lexeme)
+	if !ok {
+		return newError(s.Method.Span(), "undefined property '%s'", s.Method.Lexeme)
+	}
+
+	i.Literal = Literal{Value: method.Bind(instance)}
+
+	return nil
+}
+
+func (i *Interpreter) visitThisExpr(t ThisExpr) error {
+	distance, _ := i.Locals[localKey(t.Keyword)]
+
+	e, err := i.Environment.Get(Variable{Token: t.Keyword}, distance)
+	if err != nil {
+		return err
+	}
+
+	i.Literal = e.(Literal)
+
+	return nil
+}
+
 func (i *Interpreter) visitUnary(u Unary) error {
 	if _, err := i.Evaluate(u.Right); err != nil {
 		return err
 	}
 
 	invalidOperand := func(operand interface{}) error {
-		return fmt.Errorf("error at line %d: bad operand for unary %s: %T", u.Operator.Line, u.Operator.Lexeme, operand)
+		return newError(u.Operator.Span(), "bad operand for unary %s: %T", u.Operator.Lexeme, operand)
 	}
 
 	switch u.Operator.TokenType {
 	case Not:
 		{
-			i.Literal = Literal{!i.Literal.Bool()}
+			i.Literal = Literal{Value: !i.Literal.Bool()}
 		}
 	case Minus:
 		{
 			if f, ok := i.Literal.Value.(float64); ok {
-				i.Literal = Literal{-f}
+				i.Literal = Literal{Value: -f}
 			} else {
 				return invalidOperand(i.Literal.Value)
 			}
@@ -459,7 +909,7 @@ func (i *Interpreter) visitUnary(u Unary) error {
 }
 
 func (i *Interpreter) visitVariable(v Variable) error {
-	distance, _ := i.Locals[fmt.Sprintf("%v%v", v.Lexeme, v.Line)]
+	distance, _ := i.Locals[localKey(v.Token)]
 
 	e, err := i.Environment.Get(v, distance)
 	if err != nil {
@@ -473,6 +923,48 @@ func (i *Interpreter) visitVariable(v Variable) error {
 	return nil
 }
 
+func (i *Interpreter) visitThrowStmt(t ThrowStmt) error {
+	l, err := i.Evaluate(t.Expr)
+	if err != nil {
+		return err
+	}
+
+	return newThrown(l, t.Span, i.file)
+}
+
+// visitTryStmt runs the finally block on the way out of the statement, even
+// when it is left by return, break or continue. An error in the finally block
+// replaces the one it was run for.
+func (i *Interpreter) visitTryStmt(t TryStmt) error {
+	err := t.Body.Accept(i)
+
+	if value, ok := caught(err); ok && t.Catch != nil {
+		err = i.runCatch(t, value)
+	}
+
+	if t.Finally != nil {
+		if err := t.Finally.Accept(i); err != nil {
+			return err
+		}
+	}
+
+	return err
+}
+
+func (i *Interpreter) runCatch(t TryStmt, value Literal) error {
+	previous := i.Environment
+	defer func() {
+		i.Environment = previous
+	}()
+
+	i.Environment = NewEnvironment(i.Environment)
+	if err := i.Environment.Declare(Variable{Token: t.Name}, value); err != nil {
+		return err
+	}
+
+	return t.Catch.Accept(i)
+}
+
 func (i *Interpreter) visitWhileStmt(w WhileStmt) error {
 	for true {
 		l, err := i.Evaluate(w.Condition)
@@ -484,10 +976,83 @@ func (i *Interpreter) visitWhileStmt(w WhileStmt) error {
 			return nil
 		}
 
-		if err := w.Body.Accept(i); err != nil {
+		if done, err := i.runLoopBody(w.Body); done || err != nil {
 			return err
 		}
 	}
 
 	return nil
 }
+
+// isEqual reports whether two values are equal. Lists and maps are equal when
+// they hold equal elements, functions when they come from the same declaration
+// and closure.
+func isEqual(a, b interface{}) bool {
+	return equal(a, b, nil)
+}
+
+// comparison is a pair of containers being compared.
+type comparison struct {
+	a, b interface{}
+}
+
+// equal reports whether a and b are equal. The containers being compared are
+// in seen: when a comparison comes back to them through a cycle, they are
+// taken as equal, leaving the decision to their other elements.
+func equal(a, b interface{}, seen map[comparison]bool) bool {
+	switch l := a.(type) {
+	case *List:
+		r, ok := b.(*List)
+		if !ok || len(l.Elements) != len(r.Elements) {
+			return false
+		}
+
+		if l == r || seen[comparison{l, r}] {
+			return true
+		}
+
+		if seen == nil {
+			seen = make(map[comparison]bool)
+		}
+		seen[comparison{l, r}] = true
+
+		for j := range l.Elements {
+			if !equal(l.Elements[j].Value, r.Elements[j].Value, seen) {
+				return false
+			}
+		}
+
+		return true
+	case *Map:
+		r, ok := b.(*Map)
+		if !ok || l.Len() != r.Len() {
+			return false
+		}
+
+		if l == r || seen[comparison{l, r}] {
+			return true
+		}
+
+		if seen == nil {
+			seen = make(map[comparison]bool)
+		}
+		seen[comparison{l, r}] = true
+
+		for k, v := range l.values {
+			w, ok := r.values[k]
+			if !ok || !equal(v.Value, w.Value, seen) {
+				return false
+			}
+		}
+
+		return true
+	case Function:
+		r, ok := b.(Function)
+		return ok && l.Span == r.Span && l.Closure == r.Closure
+	case Native:
+		r, ok := b.(Native)
+		return ok && l.name == r.name
+	}
+
+	return a == b
+}
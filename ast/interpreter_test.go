@@ -0,0 +1,150 @@
+//  MIT License
+//
+//  Copyright (c) 2019 Marco Pacini
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy
+//  of this software and associated documentation files (the "Software"), to deal
+//  in the Software without restriction, including without limitation the rights
+//  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//  copies of the Software, and to permit persons to whom the Software is
+//  furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all
+//  copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//  SOFTWARE.
+
+package ast
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestInterpreter_BreakContinue(t *testing.T) {
+	table := []struct {
+		in  string
+		out interface{}
+	}{
+		{"var a = 0; while (true) { a = a + 1; if (a == 3) break; }", 3.0},
+		{"var a = 0; var i = 0; while (i < 5) { i = i + 1; if (i < 3) continue; a = a + i; }", 12.0},
+		{"var a = 0; for (var i = 0; i < 5; i = i + 1) { if (i == 2) continue; a = a + i; }", 8.0},
+		{"var a = 0; for (var i = 0; i < 5; i = i + 1) { if (i == 3) break; a = a + i; }", 3.0},
+		{"var a = 0; for (var i = 0; i < 3; i = i + 1) { for (var j = 0; j < 3; j = j + 1) { if (j == 1) break; a = a + 1; } }", 3.0},
+		{"var a = 0; for (var i = 0; i < 3; i = i + 1) { for (var j = 0; j < 3; j = j + 1) { if (j == 1) continue; a = a + 1; } }", 6.0},
+		{"var a = 0; for (var i = 0; i < 3; i = i + 1) { { var b = i; if (b == 1) continue; } a = a + 1; }", 2.0},
+		{"fun f() { while (true) { return 7; } } var a = f();", 7.0},
+	}
+
+	for _, test := range table {
+		t.Run(test.in, func(t *testing.T) {
+			i, err := run(test.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if a := i.Globals.Scope["a"].(Literal).Value; a != test.out {
+				t.Errorf("expected %v, got %v", test.out, a)
+			}
+		})
+	}
+}
+
+func TestInterpreter_BreakContinueErrors(t *testing.T) {
+	table := []struct {
+		in  string
+		out string
+	}{
+		{"break;", "cannot use 'break' outside of a loop"},
+		{"if (true) continue;", "cannot use 'continue' outside of a loop"},
+		{"while (true) { fun f() { break; } }", "cannot use 'break' outside of a loop"},
+	}
+
+	for _, test := range table {
+		t.Run(test.in, func(t *testing.T) {
+			if _, err := run(test.in); err == nil || !strings.Contains(err.Error(), test.out) {
+				t.Errorf("expected error %q, got %v", test.out, err)
+			}
+		})
+	}
+}
+
+func run(source string) (*Interpreter, error) {
+	tokens, err := (&Scanner{source}).Scan()
+	if err != nil {
+		return nil, err
+	}
+
+	stmts, err := (&Parser{Tokens: tokens}).Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	i := NewInterpreter()
+	return i, i.Run(stmts)
+}
+
+func TestInterpreter_MaxCallDepth(t *testing.T) {
+	source := "fun sum(n) { if (n == 0) return 0; return n + sum(n - 1); } var a = sum(1000);"
+
+	i, err := run(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if a := i.Globals.Scope["a"].(Literal).Value; a != 500500.0 {
+		t.Errorf("expected 500500, got %v", a)
+	}
+
+	tokens, err := (&Scanner{source}).Scan()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stmts, err := (&Parser{Tokens: tokens}).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	i = NewInterpreter()
+	i.MaxCallDepth = 100
+
+	if err := i.Run(stmts); err == nil || !strings.Contains(err.Error(), "stack overflow") {
+		t.Errorf("expected a stack overflow, got %v", err)
+	}
+}
+
+// evaluate runs "var a = expr;" on i and returns the value of a.
+func evaluate(i *Interpreter, expr string) (interface{}, error) {
+	tokens, err := (&Scanner{"var a = " + expr + ";"}).Scan()
+	if err != nil {
+		return nil, err
+	}
+
+	stmts, err := (&Parser{Tokens: tokens}).Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := i.Run(stmts); err != nil {
+		return nil, err
+	}
+
+	return i.Globals.Scope["a"].(Literal).Value, nil
+}
+
+// message returns the message of a runtime error, or err itself as text.
+func message(err error) string {
+	if e, ok := err.(RuntimeError); ok {
+		return e.Message
+	}
+
+	return fmt.Sprint(err)
+}
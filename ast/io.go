@@ -0,0 +1,143 @@
+//  MIT License
+//
+//  Copyright (c) 2019 Marco Pacini
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy
+//  of this software and associated documentation files (the "Software"), to deal
+//  in the Software without restriction, including without limitation the rights
+//  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//  copies of the Software, and to permit persons to whom the Software is
+//  furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all
+//  copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//  SOFTWARE.
+
+package ast
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// stdin returns the buffered standard input of the interpreter.
+func (i *Interpreter) stdin() *bufio.Reader {
+	if i.input == nil || i.inputSource != i.Stdin {
+		i.input, i.inputSource = bufio.NewReader(i.Stdin), i.Stdin
+	}
+
+	return i.input
+}
+
+// pathArgument returns the first argument of the native name, a file path.
+func pathArgument(name string, arguments []Literal) (string, error) {
+	path, ok := arguments[0].Value.(string)
+	if !ok {
+		return "", fmt.Errorf("%s: expected path, got %v", name, arguments[0])
+	}
+
+	return path, nil
+}
+
+// writer returns a native writing its second argument to the file in the first
+// one, opened with flag.
+func writer(name string, flag int) Native {
+	return NewNative(name, 2, func(i *Interpreter, arguments []Literal) (Literal, error) {
+		path, err := pathArgument(name, arguments)
+		if err != nil {
+			return Literal{}, err
+		}
+
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|flag, 0644)
+		if err != nil {
+			return Literal{}, fmt.Errorf("%s: %v", name, err)
+		}
+
+		if _, err := f.WriteString(arguments[1].String()); err != nil {
+			f.Close()
+			return Literal{}, fmt.Errorf("%s: %v", name, err)
+		}
+
+		if err := f.Close(); err != nil {
+			return Literal{}, fmt.Errorf("%s: %v", name, err)
+		}
+
+		return Literal{}, nil
+	})
+}
+
+var ioNatives = []Native{
+	// readLine returns the next line of the standard input, without the line
+	// terminator, or nil at the end of the input
+	NewNative("readLine", 0, func(i *Interpreter, arguments []Literal) (Literal, error) {
+		line, err := i.stdin().ReadString('\n')
+		if err == io.EOF && line == "" {
+			return Literal{}, nil
+		}
+
+		if err != nil && err != io.EOF {
+			return Literal{}, fmt.Errorf("readLine: %v", err)
+		}
+
+		return Literal{Value: strings.TrimRight(line, "\r\n")}, nil
+	}),
+	NewNative("printErr", 1, func(i *Interpreter, arguments []Literal) (Literal, error) {
+		fmt.Fprintln(i.Stderr, arguments[0])
+		return Literal{}, nil
+	}),
+	NewNative("readFile", 1, func(i *Interpreter, arguments []Literal) (Literal, error) {
+		path, err := pathArgument("readFile", arguments)
+		if err != nil {
+			return Literal{}, err
+		}
+
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return Literal{}, fmt.Errorf("readFile: %v", err)
+		}
+
+		return Literal{Value: string(b)}, nil
+	}),
+	writer("writeFile", os.O_TRUNC),
+	writer("appendFile", os.O_APPEND),
+	NewNative("exists", 1, func(i *Interpreter, arguments []Literal) (Literal, error) {
+		path, err := pathArgument("exists", arguments)
+		if err != nil {
+			return Literal{}, err
+		}
+
+		_, err = os.Stat(path)
+
+		return Literal{Value: err == nil}, nil
+	}),
+	// listDir returns the names of the entries of a directory, sorted
+	NewNative("listDir", 1, func(i *Interpreter, arguments []Literal) (Literal, error) {
+		path, err := pathArgument("listDir", arguments)
+		if err != nil {
+			return Literal{}, err
+		}
+
+		entries, err := ioutil.ReadDir(path)
+		if err != nil {
+			return Literal{}, fmt.Errorf("listDir: %v", err)
+		}
+
+		names := make([]Literal, len(entries))
+		for j, entry := range entries {
+			names[j] = Literal{Value: entry.Name()}
+		}
+
+		return Literal{Value: NewList(names)}, nil
+	}),
+}
@@ -0,0 +1,101 @@
+//  MIT License
+//
+//  Copyright (c) 2019 Marco Pacini
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy
+//  of this software and associated documentation files (the "Software"), to deal
+//  in the Software without restriction, including without limitation the rights
+//  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//  copies of the Software, and to permit persons to whom the Software is
+//  furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all
+//  copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//  SOFTWARE.
+
+package ast
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileNatives(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	i := NewInterpreter()
+	i.Globals.Declare(Variable{Token: Token{TokenType: Identifier, Lexeme: "dir"}}, Literal{Value: dir})
+
+	notes := filepath.Join(dir, "notes.txt")
+
+	// the error listing a file depends on the system
+	if err := ioutil.WriteFile(notes, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, notDir := ioutil.ReadDir(notes)
+	os.Remove(notes)
+
+	// the steps run in order, on the same files
+	table := []struct {
+		in  string
+		out interface{}
+		err string
+	}{
+		{"dir + \"/notes.txt\"", notes, ""},
+		{"exists(dir + \"/notes.txt\")", false, ""},
+		{"writeFile(dir + \"/notes.txt\", \"first\n\")", nil, ""},
+		{"appendFile(dir + \"/notes.txt\", 2)", nil, ""},
+		{"readFile(dir + \"/notes.txt\")", "first\n2", ""},
+		{"exists(dir + \"/notes.txt\")", true, ""},
+		{"writeFile(dir + \"/notes.txt\", \"over\")", nil, ""},
+		{"readFile(dir + \"/notes.txt\")", "over", ""},
+		{"appendFile(dir + \"/log.txt\", \"new\")", nil, ""},
+		{"join(listDir(dir), \",\")", "log.txt,notes.txt", ""},
+		{"readFile(dir + \"/missing.txt\")", nil, "readFile: open " + filepath.Join(dir, "missing.txt") + ": no such file or directory"},
+		{"listDir(dir + \"/missing\")", nil, "listDir: open " + filepath.Join(dir, "missing") + ": no such file or directory"},
+		{"writeFile(dir + \"/missing/a.txt\", \"\")", nil, "writeFile: open " + filepath.Join(dir, "missing", "a.txt") + ": no such file or directory"},
+		{"listDir(dir + \"/notes.txt\")", nil, "listDir: " + notDir.Error()},
+		{"readFile(1)", nil, "readFile: expected path, got 1"},
+		{"writeFile(nil, \"\")", nil, "writeFile: expected path, got nil"},
+		{"appendFile(true, \"\")", nil, "appendFile: expected path, got true"},
+		{"listDir([dir])", nil, "listDir: expected path, got [\"" + dir + "\"]"},
+		{"exists(0)", nil, "exists: expected path, got 0"},
+	}
+
+	for _, test := range table {
+		out, err := evaluate(i, test.in)
+
+		if test.err != "" {
+			if message(err) != test.err {
+				t.Errorf("%s: want error %q, got %v", test.in, test.err, err)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("%s: %v", test.in, err)
+		}
+
+		if out != test.out {
+			t.Errorf("%s: want %q, got %q", test.in, test.out, out)
+		}
+	}
+
+	if b, err := ioutil.ReadFile(notes); err != nil || string(b) != "over" {
+		t.Errorf("want file %q, got %q (%v)", "over", b, err)
+	}
+}
@@ -0,0 +1,512 @@
+//  MIT License
+//
+//  Copyright (c) 2019 Marco Pacini
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy
+//  of this software and associated documentation files (the "Software"), to deal
+//  in the Software without restriction, including without limitation the rights
+//  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//  copies of the Software, and to permit persons to whom the Software is
+//  furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all
+//  copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//  SOFTWARE.
+
+package ast
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type bindingKind int
+
+const (
+	variableBinding bindingKind = iota
+	parameterBinding
+	functionBinding
+	classBinding
+	// catch variables and imports are never reported as unused
+	otherBinding
+)
+
+// binding is a name declared in the program being linted.
+type binding struct {
+	Token
+	kind bindingKind
+	// arity is the number of arguments a call takes, or -1 if unknown
+	arity    int
+	used     bool
+	assigned bool
+}
+
+// call is a call to a variable, checked against the arity of the variable
+// once the whole program has been seen.
+type call struct {
+	binding *binding
+	name    string
+	count   int
+	Span
+}
+
+// Linter finds likely mistakes in a program: unused locals and parameters,
+// shadowed and redeclared variables, unreachable code, returns outside of
+// functions and calls with the wrong number of arguments.
+type Linter struct {
+	scopes   []map[string]*binding
+	builtins map[string]int
+	function functionType
+	calls    []call
+	warnings []Warning
+}
+
+// Lint returns the warnings for stmts, which must have been resolved without
+// errors, in source order.
+func Lint(stmts []Stmt) []Warning {
+	l := Linter{builtins: map[string]int{"clock": 0}}
+
+	for _, natives := range builtinNatives {
+		for _, n := range natives {
+			if n.arity >= 0 {
+				l.builtins[n.name] = n.arity
+			}
+		}
+	}
+
+	l.beginScope()
+	l.statements(stmts)
+	l.checkCalls()
+
+	sort.SliceStable(l.warnings, func(i, j int) bool {
+		return l.warnings[i].Offset < l.warnings[j].Offset
+	})
+
+	return l.warnings
+}
+
+func (l *Linter) warn(span Span, format string, a ...interface{}) {
+	l.warnings = append(l.warnings, Warning{span, fmt.Sprintf(format, a...)})
+}
+
+func (l *Linter) beginScope() {
+	l.scopes = append(l.scopes, make(map[string]*binding))
+}
+
+// endScope closes the innermost scope, reporting the locals never read.
+func (l *Linter) endScope() {
+	scope := l.scopes[len(l.scopes)-1]
+	l.scopes = l.scopes[:len(l.scopes)-1]
+
+	// globals can be used by other programs, as modules or in the REPL
+	if len(l.scopes) == 0 {
+		return
+	}
+
+	for name, b := range scope {
+		if b.used || strings.HasPrefix(name, "_") {
+			continue
+		}
+
+		switch b.kind {
+		case variableBinding:
+			l.warn(b.Span(), "unused variable %s", name)
+		case parameterBinding:
+			l.warn(b.Span(), "unused parameter %s", name)
+		case functionBinding:
+			l.warn(b.Span(), "unused function %s", name)
+		case classBinding:
+			l.warn(b.Span(), "unused class %s", name)
+		}
+	}
+}
+
+func (l *Linter) declare(name Token, kind bindingKind, arity int) {
+	scope := l.scopes[len(l.scopes)-1]
+	local := len(l.scopes) > 1
+
+	if previous, ok := scope[name.Lexeme]; ok && local {
+		l.warn(name.Span(), "%s is already declared in this scope, at line %d", name.Lexeme, previous.Line)
+	} else if previous, ok := l.lookup(name.Lexeme); ok && local && previous.kind != otherBinding {
+		l.warn(name.Span(), "declaration of %s shadows the one at line %d", name.Lexeme, previous.Line)
+	}
+
+	scope[name.Lexeme] = &binding{name, kind, arity, false, false}
+}
+
+func (l *Linter) lookup(name string) (*binding, bool) {
+	for j := len(l.scopes) - 1; j >= 0; j-- {
+		if b, ok := l.scopes[j][name]; ok {
+			return b, true
+		}
+	}
+
+	return nil, false
+}
+
+// statements lints a list of statements, reporting the first one that can
+// never run.
+func (l *Linter) statements(stmts []Stmt) {
+	for j, stmt := range stmts {
+		stmt.Accept(l)
+
+		if j+1 < len(stmts) && terminates(stmt) {
+			l.warn(StmtSpan(stmts[j+1]), "unreachable code")
+
+			for _, stmt := range stmts[j+1:] {
+				stmt.Accept(l)
+			}
+
+			return
+		}
+	}
+}
+
+// terminates reports whether the statements after stmt are never run.
+func terminates(stmt Stmt) bool {
+	switch s := stmt.(type) {
+	case ReturnStmt, ThrowStmt, BreakStmt, ContinueStmt:
+		return true
+	case Block:
+		for _, stmt := range s.Stmts {
+			if terminates(stmt) {
+				return true
+			}
+		}
+	case IfStmt:
+		return s.Else != nil && terminates(s.Then) && terminates(s.Else)
+	}
+
+	return false
+}
+
+// checkCalls reports the calls with the wrong number of arguments, to
+// variables that are never assigned after their declaration.
+func (l *Linter) checkCalls() {
+	for _, c := range l.calls {
+		arity := -1
+
+		b := c.binding
+		if b == nil {
+			b, _ = l.scopes[0][c.name]
+		}
+
+		if b != nil && !b.assigned {
+			arity = b.arity
+		} else if a, ok := l.builtins[c.name]; ok && b == nil {
+			arity = a
+		}
+
+		if arity >= 0 && arity != c.count {
+			l.warn(c.Span, "%s expects %d arguments but got %d", c.name, arity, c.count)
+		}
+	}
+}
+
+func (l *Linter) lintFunction(f Function, kind functionType) {
+	enclosing := l.function
+	l.function = kind
+
+	defer func() {
+		l.function = enclosing
+	}()
+
+	l.beginScope()
+	for _, argument := range f.Arguments {
+		l.declare(argument, parameterBinding, -1)
+	}
+
+	l.statements(f.Body)
+	l.endScope()
+}
+
+func (l *Linter) visitAssign(a Assign) error {
+	a.Expr.Accept(l)
+
+	if b, ok := l.lookup(a.Variable.Lexeme); ok {
+		b.assigned = true
+	}
+
+	return nil
+}
+
+func (l *Linter) visitBinary(b Binary) error {
+	b.Left.Accept(l)
+	b.Right.Accept(l)
+
+	return nil
+}
+
+func (l *Linter) visitBlock(b Block) error {
+	l.beginScope()
+	l.statements(b.Stmts)
+	l.endScope()
+
+	return nil
+}
+
+func (l *Linter) visitBreakStmt(b BreakStmt) error {
+	return nil
+}
+
+func (l *Linter) visitCall(c Call) error {
+	c.Callee.Accept(l)
+
+	for _, argument := range c.Arguments {
+		argument.Accept(l)
+	}
+
+	if v, ok := c.Callee.(Variable); ok {
+		b, _ := l.lookup(v.Lexeme)
+
+		// globals declared later are looked up at the end
+		if b != nil && b == l.scopes[0][v.Lexeme] {
+			b = nil
+		}
+
+		l.calls = append(l.calls, call{b, v.Lexeme, len(c.Arguments), c.Span})
+	}
+
+	return nil
+}
+
+func (l *Linter) visitClassStmt(c ClassStmt) error {
+	// without an init of its own, a class takes the arguments of its
+	// superclass, if known
+	arity := 0
+	if c.Superclass != nil {
+		arity = -1
+		if b, ok := l.lookup(c.Superclass.Lexeme); ok && b.kind == classBinding && !b.assigned {
+			arity = b.arity
+		}
+	}
+
+	for _, m := range c.Methods {
+		if m.Name.Lexeme == "init" {
+			arity = m.Arity()
+		}
+	}
+
+	l.declare(c.Name, classBinding, arity)
+
+	if c.Superclass != nil {
+		c.Superclass.Accept(l)
+	}
+
+	for _, m := range c.Methods {
+		kind := method
+		if m.Name.Lexeme == "init" {
+			kind = initializer
+		}
+
+		l.lintFunction(m, kind)
+	}
+
+	return nil
+}
+
+func (l *Linter) visitContinueStmt(c ContinueStmt) error {
+	return nil
+}
+
+func (l *Linter) visitDeclaration(d Declaration) error {
+	arity := -1
+
+	if d.Expr != nil {
+		d.Expr.Accept(l)
+
+		if lambda, ok := d.Expr.(Lambda); ok {
+			arity = lambda.Function.Arity()
+		}
+	}
+
+	l.declare(d.Token, variableBinding, arity)
+
+	return nil
+}
+
+func (l *Linter) visitExprStmt(e ExprStmt) error {
+	return e.Expr.Accept(l)
+}
+
+// visitForStmt scopes the loop variable to the loop, so that loops declaring
+// the same variable one after the other are not reported.
+func (l *Linter) visitForStmt(f ForStmt) error {
+	l.beginScope()
+	defer l.endScope()
+
+	if f.Init != nil {
+		f.Init.Accept(l)
+	}
+
+	if f.Condition != nil {
+		f.Condition.Accept(l)
+	}
+
+	if f.Increment != nil {
+		f.Increment.Accept(l)
+	}
+
+	return f.Body.Accept(l)
+}
+
+func (l *Linter) visitFunction(f Function) error {
+	l.declare(f.Name, functionBinding, f.Arity())
+	l.lintFunction(f, plainFunction)
+
+	return nil
+}
+
+func (l *Linter) visitGet(g Get) error {
+	return g.Object.Accept(l)
+}
+
+func (l *Linter) visitGrouping(g Grouping) error {
+	return g.Expr.Accept(l)
+}
+
+func (l *Linter) visitIfStmt(i IfStmt) error {
+	i.Condition.Accept(l)
+	i.Then.Accept(l)
+
+	if i.Else != nil {
+		i.Else.Accept(l)
+	}
+
+	return nil
+}
+
+func (l *Linter) visitImportStmt(i ImportStmt) error {
+	l.declare(Token{TokenType: Identifier, Lexeme: i.name(), Line: i.Keyword.Line}, otherBinding, -1)
+	return nil
+}
+
+func (l *Linter) visitIndex(i Index) error {
+	i.Object.Accept(l)
+	return i.Index.Accept(l)
+}
+
+func (l *Linter) visitIndexSet(i IndexSet) error {
+	i.Object.Accept(l)
+	i.Index.Accept(l)
+
+	return i.Value.Accept(l)
+}
+
+func (l *Linter) visitLambda(lambda Lambda) error {
+	l.lintFunction(lambda.Function, plainFunction)
+	return nil
+}
+
+func (l *Linter) visitListExpr(list ListExpr) error {
+	for _, element := range list.Elements {
+		element.Accept(l)
+	}
+
+	return nil
+}
+
+func (l *Linter) visitLiteral(literal Literal) error {
+	return nil
+}
+
+func (l *Linter) visitLogical(logical Logical) error {
+	logical.Left.Accept(l)
+	return logical.Right.Accept(l)
+}
+
+func (l *Linter) visitMapExpr(m MapExpr) error {
+	for j := range m.Keys {
+		m.Keys[j].Accept(l)
+		m.Values[j].Accept(l)
+	}
+
+	return nil
+}
+
+func (l *Linter) visitPrintStmt(p PrintStmt) error {
+	return p.Expr.Accept(l)
+}
+
+func (l *Linter) visitReturnStmt(r ReturnStmt) error {
+	if l.function == noFunction {
+		l.warn(r.Keyword.Span(), "return outside of a function")
+	}
+
+	if r.Expr != nil {
+		r.Expr.Accept(l)
+	}
+
+	return nil
+}
+
+func (l *Linter) visitSet(s Set) error {
+	s.Object.Accept(l)
+	return s.Value.Accept(l)
+}
+
+func (l *Linter) visitSlice(s Slice) error {
+	s.Object.Accept(l)
+
+	for _, bound := range []Expr{s.Start, s.End} {
+		if bound != nil {
+			bound.Accept(l)
+		}
+	}
+
+	return nil
+}
+
+func (l *Linter) visitSuperExpr(s SuperExpr) error {
+	return nil
+}
+
+func (l *Linter) visitThisExpr(t ThisExpr) error {
+	return nil
+}
+
+func (l *Linter) visitThrowStmt(t ThrowStmt) error {
+	return t.Expr.Accept(l)
+}
+
+func (l *Linter) visitTryStmt(t TryStmt) error {
+	t.Body.Accept(l)
+
+	if t.Catch != nil {
+		l.beginScope()
+		l.declare(t.Name, otherBinding, -1)
+		t.Catch.Accept(l)
+		l.endScope()
+	}
+
+	if t.Finally != nil {
+		t.Finally.Accept(l)
+	}
+
+	return nil
+}
+
+func (l *Linter) visitUnary(u Unary) error {
+	return u.Right.Accept(l)
+}
+
+func (l *Linter) visitVariable(v Variable) error {
+	if b, ok := l.lookup(v.Lexeme); ok {
+		b.used = true
+	}
+
+	return nil
+}
+
+func (l *Linter) visitWhileStmt(w WhileStmt) error {
+	w.Condition.Accept(l)
+	return w.Body.Accept(l)
+}
@@ -0,0 +1,91 @@
+//  MIT License
+//
+//  Copyright (c) 2019 Marco Pacini
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy
+//  of this software and associated documentation files (the "Software"), to deal
+//  in the Software without restriction, including without limitation the rights
+//  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//  copies of the Software, and to permit persons to whom the Software is
+//  furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all
+//  copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//  SOFTWARE.
+
+package ast
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	table := []struct {
+		in       string
+		warnings []string
+	}{
+		{"var a = 1; fun f(x) { return x; } print f(a);", nil},
+		{"fun f(a, b) { var c; return a; }", []string{"1: unused parameter b", "1: unused variable c"}},
+		{"fun f(_a) { fun g() {} class C {} }", []string{"1: unused function g", "1: unused class C"}},
+		{"var a; fun f() { var a = 1; print a; }", []string{"1: declaration of a shadows the one at line 1"}},
+		{"{ var a = 1;\nvar a = 2; print a; }", []string{"2: a is already declared in this scope, at line 1"}},
+		{"fun f() { return 1;\nprint 2; }", []string{"2: unreachable code"}},
+		{"while (true) { break;\nprint 1; }", []string{"2: unreachable code"}},
+		{"fun f(c) { if (c) return 1; else return 2;\nprint \"dead\"; }", []string{"2: unreachable code"}},
+		{"fun f(c) { if (c) return 1;\nprint \"live\"; }", nil},
+		{"fun f() { { print 1; return 1; }\nprint 2; }", []string{"2: unreachable code"}},
+		{"while (true) { if (true) { break; } else continue;\nprint 1; }", []string{"2: unreachable code"}},
+		{"print 1;\nreturn;", []string{"2: return outside of a function"}},
+		{"fun f(a) { return a; }\nf();\nf(1);", []string{"2: f expects 1 arguments but got 0"}},
+		{"class A { init(a, b) { this.a = a + b; } }\nA(1);", []string{"2: A expects 2 arguments but got 1"}},
+		{"class A { init(x) { this.x = x; } } class B < A {} B(1);", nil},
+		{"class A { init(x) { this.x = x; } } class B < A {}\nB();", []string{"2: B expects 1 arguments but got 0"}},
+		{"class A { init(x) { this.x = x; } } class B < A { init() {} }\nB(1);", []string{"2: B expects 0 arguments but got 1"}},
+		{"class A {} var C = A; class B < C {} B(1);", nil},
+		{"var f = fun (a) => a;\nf(1, 2);", []string{"2: f expects 1 arguments but got 2"}},
+		{"var f = fun (a) => a; f = clock; f();", nil},
+		{"fun g() { h(1); }\nfun h() {}", []string{"1: h expects 0 arguments but got 1"}},
+		{"len();", []string{"1: len expects 1 arguments but got 0"}},
+		{"for (var i = 0; i < 1; i = i + 1) {} for (var i = 0; i < 1; i = i + 1) {}", nil},
+		{"try {} catch (e) {}", nil},
+	}
+
+	for _, test := range table {
+		t.Run(test.in, func(t *testing.T) {
+			scanner := Scanner{Text: test.in}
+			tokens, err := scanner.Scan()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			parser := Parser{Tokens: tokens}
+			stmts, err := parser.Parse()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var r Resolver
+			if err := r.Resolve(stmts); err != nil {
+				t.Fatal(err)
+			}
+
+			var warnings []string
+			for _, w := range Lint(stmts) {
+				warnings = append(warnings, fmt.Sprintf("%d: %s", w.Line, w.Message))
+			}
+
+			if !reflect.DeepEqual(warnings, test.warnings) {
+				t.Errorf("want %q, got %q", test.warnings, warnings)
+			}
+		})
+	}
+}
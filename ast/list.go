@@ -0,0 +1,205 @@
+//  MIT License
+//
+//  Copyright (c) 2019 Marco Pacini
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy
+//  of this software and associated documentation files (the "Software"), to deal
+//  in the Software without restriction, including without limitation the rights
+//  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//  copies of the Software, and to permit persons to whom the Software is
+//  furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all
+//  copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//  SOFTWARE.
+
+package ast
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// List is the runtime value of a list, shared by reference like instances.
+type List struct {
+	Elements []Literal
+}
+
+func NewList(elements []Literal) *List {
+	return &List{elements}
+}
+
+// index converts l to a position in a sequence of the given length. Negative
+// indexes count from the end.
+func index(l Literal, length int) (int, error) {
+	f, ok := l.Value.(float64)
+	if !ok || f != math.Trunc(f) {
+		return 0, fmt.Errorf("index must be an integer, got %v", l)
+	}
+
+	n := int(f)
+	if n < 0 {
+		n += length
+	}
+
+	if n < 0 || n >= length {
+		return 0, fmt.Errorf("index %v out of range for length %d", l, length)
+	}
+
+	return n, nil
+}
+
+// bounds converts the optional start and end of a slice to positions in a
+// sequence of the given length, clamping them to its limits.
+func bounds(start, end Literal, length int) (int, int, error) {
+	limit := func(l Literal, missing int) (int, error) {
+		if l.Value == nil {
+			return missing, nil
+		}
+
+		f, ok := l.Value.(float64)
+		if !ok || f != math.Trunc(f) {
+			return 0, fmt.Errorf("slice bounds must be integers, got %v", l)
+		}
+
+		n := int(f)
+		if n < 0 {
+			n += length
+		}
+
+		return int(math.Max(0, math.Min(float64(n), float64(length)))), nil
+	}
+
+	from, err := limit(start, 0)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	to, err := limit(end, length)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if to < from {
+		to = from
+	}
+
+	return from, to, nil
+}
+
+func (l *List) Get(t Token, i Literal) (Literal, error) {
+	n, err := index(i, len(l.Elements))
+	if err != nil {
+		return Literal{}, newError(t.Span(), "%v", err)
+	}
+
+	return l.Elements[n], nil
+}
+
+func (l *List) Set(t Token, i Literal, value Literal) error {
+	n, err := index(i, len(l.Elements))
+	if err != nil {
+		return newError(t.Span(), "%v", err)
+	}
+
+	l.Elements[n] = value
+
+	return nil
+}
+
+// Slice returns a new list with the elements from start up to, but not
+// including, end.
+func (l *List) Slice(t Token, start, end Literal) (*List, error) {
+	from, to, err := bounds(start, end, len(l.Elements))
+	if err != nil {
+		return nil, newError(t.Span(), "%v", err)
+	}
+
+	return NewList(append([]Literal{}, l.Elements[from:to]...)), nil
+}
+
+func (l *List) String() string {
+	return l.format(make(map[interface{}]bool))
+}
+
+// format formats l, printing as [...] a list that contains itself. Seen holds
+// the containers being formatted.
+func (l *List) format(seen map[interface{}]bool) string {
+	if seen[l] {
+		return "[...]"
+	}
+
+	seen[l] = true
+	defer delete(seen, l)
+
+	elements := make([]string, len(l.Elements))
+	for j, e := range l.Elements {
+		elements[j] = formatElement(e, seen)
+	}
+
+	return fmt.Sprintf("[%s]", strings.Join(elements, ", "))
+}
+
+// formatElement formats an element of a container being formatted. Strings
+// are quoted, so that "1" and 1 differ.
+func formatElement(e Literal, seen map[interface{}]bool) string {
+	switch v := e.Value.(type) {
+	case *List:
+		return v.format(seen)
+	case *Map:
+		return v.format(seen)
+	case string:
+		return strconv.Quote(v)
+	}
+
+	return e.String()
+}
+
+var listNatives = []Native{
+	NewNative("len", 1, func(i *Interpreter, arguments []Literal) (Literal, error) {
+		switch v := arguments[0].Value.(type) {
+		case *List:
+			return Literal{Value: float64(len(v.Elements))}, nil
+		case *Map:
+			return Literal{Value: float64(v.Len())}, nil
+		case string:
+			return Literal{Value: float64(len([]rune(v)))}, nil
+		}
+
+		return Literal{}, fmt.Errorf("len: expected list, map or string, got %v", arguments[0])
+	}),
+	NewNative("push", 2, func(i *Interpreter, arguments []Literal) (Literal, error) {
+		l, ok := arguments[0].Value.(*List)
+		if !ok {
+			return Literal{}, fmt.Errorf("push: expected list, got %v", arguments[0])
+		}
+
+		l.Elements = append(l.Elements, Literal{Value: arguments[1].Value})
+
+		return Literal{Value: float64(len(l.Elements))}, nil
+	}),
+	NewNative("pop", 1, func(i *Interpreter, arguments []Literal) (Literal, error) {
+		l, ok := arguments[0].Value.(*List)
+		if !ok {
+			return Literal{}, fmt.Errorf("pop: expected list, got %v", arguments[0])
+		}
+
+		if len(l.Elements) == 0 {
+			return Literal{}, fmt.Errorf("pop: empty list")
+		}
+
+		last := l.Elements[len(l.Elements)-1]
+		l.Elements = l.Elements[:len(l.Elements)-1]
+
+		return last, nil
+	}),
+}
@@ -0,0 +1,209 @@
+//  MIT License
+//
+//  Copyright (c) 2019 Marco Pacini
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy
+//  of this software and associated documentation files (the "Software"), to deal
+//  in the Software without restriction, including without limitation the rights
+//  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//  copies of the Software, and to permit persons to whom the Software is
+//  furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all
+//  copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//  SOFTWARE.
+
+package ast
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Map is the runtime value of a map. Keys are numbers, strings, booleans or
+// nil, and are kept in insertion order.
+type Map struct {
+	keys   []interface{}
+	values map[interface{}]Literal
+}
+
+func NewMap() *Map {
+	return &Map{values: make(map[interface{}]Literal)}
+}
+
+// key returns l as a map key, or an error if its value cannot be hashed.
+func key(l Literal) (interface{}, error) {
+	switch v := l.Value.(type) {
+	case nil, bool, string:
+		return v, nil
+	case float64:
+		if math.IsNaN(v) {
+			return nil, fmt.Errorf("NaN cannot be a map key")
+		}
+
+		// -0 and 0 are the same key
+		return v + 0, nil
+	}
+
+	return nil, fmt.Errorf("invalid map key %v: keys must be numbers, strings, booleans or nil", l)
+}
+
+func (m *Map) Len() int {
+	return len(m.keys)
+}
+
+func (m *Map) Get(k Literal) (Literal, error) {
+	h, err := key(k)
+	if err != nil {
+		return Literal{}, err
+	}
+
+	if v, ok := m.values[h]; ok {
+		return v, nil
+	}
+
+	return Literal{}, fmt.Errorf("undefined key %v", k)
+}
+
+func (m *Map) Set(k Literal, value Literal) error {
+	h, err := key(k)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := m.values[h]; !ok {
+		m.keys = append(m.keys, h)
+	}
+
+	m.values[h] = value
+
+	return nil
+}
+
+func (m *Map) Has(k Literal) (bool, error) {
+	h, err := key(k)
+	if err != nil {
+		return false, err
+	}
+
+	_, ok := m.values[h]
+
+	return ok, nil
+}
+
+// Remove deletes k from the map, returning its value or nil if it was missing.
+func (m *Map) Remove(k Literal) (Literal, error) {
+	h, err := key(k)
+	if err != nil {
+		return Literal{}, err
+	}
+
+	v, ok := m.values[h]
+	if !ok {
+		return Literal{}, nil
+	}
+
+	delete(m.values, h)
+
+	for j, k := range m.keys {
+		if k == h {
+			m.keys = append(m.keys[:j], m.keys[j+1:]...)
+			break
+		}
+	}
+
+	return v, nil
+}
+
+func (m *Map) Keys() []Literal {
+	keys := make([]Literal, len(m.keys))
+	for j, k := range m.keys {
+		keys[j] = Literal{Value: k}
+	}
+
+	return keys
+}
+
+func (m *Map) Values() []Literal {
+	values := make([]Literal, len(m.keys))
+	for j, k := range m.keys {
+		values[j] = m.values[k]
+	}
+
+	return values
+}
+
+func (m *Map) String() string {
+	return m.format(make(map[interface{}]bool))
+}
+
+// format formats m as List.format does, printing as {...} a map that contains
+// itself.
+func (m *Map) format(seen map[interface{}]bool) string {
+	if seen[m] {
+		return "{...}"
+	}
+
+	seen[m] = true
+	defer delete(seen, m)
+
+	entries := make([]string, len(m.keys))
+	for j, k := range m.keys {
+		entries[j] = formatElement(Literal{Value: k}, seen) + ": " + formatElement(m.values[k], seen)
+	}
+
+	return fmt.Sprintf("{%s}", strings.Join(entries, ", "))
+}
+
+var mapNatives = []Native{
+	NewNative("has", 2, func(i *Interpreter, arguments []Literal) (Literal, error) {
+		m, ok := arguments[0].Value.(*Map)
+		if !ok {
+			return Literal{}, fmt.Errorf("has: expected map, got %v", arguments[0])
+		}
+
+		ok, err := m.Has(arguments[1])
+		if err != nil {
+			return Literal{}, fmt.Errorf("has: %v", err)
+		}
+
+		return Literal{Value: ok}, nil
+	}),
+	NewNative("remove", 2, func(i *Interpreter, arguments []Literal) (Literal, error) {
+		m, ok := arguments[0].Value.(*Map)
+		if !ok {
+			return Literal{}, fmt.Errorf("remove: expected map, got %v", arguments[0])
+		}
+
+		v, err := m.Remove(arguments[1])
+		if err != nil {
+			return Literal{}, fmt.Errorf("remove: %v", err)
+		}
+
+		return v, nil
+	}),
+	NewNative("keys", 1, func(i *Interpreter, arguments []Literal) (Literal, error) {
+		m, ok := arguments[0].Value.(*Map)
+		if !ok {
+			return Literal{}, fmt.Errorf("keys: expected map, got %v", arguments[0])
+		}
+
+		return Literal{Value: NewList(m.Keys())}, nil
+	}),
+	NewNative("values", 1, func(i *Interpreter, arguments []Literal) (Literal, error) {
+		m, ok := arguments[0].Value.(*Map)
+		if !ok {
+			return Literal{}, fmt.Errorf("values: expected map, got %v", arguments[0])
+		}
+
+		return Literal{Value: NewList(m.Values())}, nil
+	}),
+}
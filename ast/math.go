@@ -0,0 +1,152 @@
+//  MIT License
+//
+//  Copyright (c) 2019 Marco Pacini
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy
+//  of this software and associated documentation files (the "Software"), to deal
+//  in the Software without restriction, including without limitation the rights
+//  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//  copies of the Software, and to permit persons to whom the Software is
+//  furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all
+//  copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//  SOFTWARE.
+
+package ast
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+var mathConstants = map[string]float64{
+	"pi":  math.Pi,
+	"inf": math.Inf(1),
+	"nan": math.NaN(),
+}
+
+// numberArgument returns the j-th argument of the native name, which must be
+// a number.
+func numberArgument(name string, arguments []Literal, j int) (float64, error) {
+	f, ok := arguments[j].Value.(float64)
+	if !ok {
+		return 0, fmt.Errorf("%s: argument %d: expected number, got %v", name, j+1, arguments[j])
+	}
+
+	return f, nil
+}
+
+// mathFunction returns a native applying fn to a single number.
+func mathFunction(name string, fn func(float64) float64) Native {
+	return NewNative(name, 1, func(i *Interpreter, arguments []Literal) (Literal, error) {
+		f, err := numberArgument(name, arguments, 0)
+		if err != nil {
+			return Literal{}, err
+		}
+
+		return Literal{Value: fn(f)}, nil
+	})
+}
+
+// mathReduce returns a native folding one or more numbers with fn.
+func mathReduce(name string, fn func(float64, float64) float64) Native {
+	return NewNative(name, -1, func(i *Interpreter, arguments []Literal) (Literal, error) {
+		if len(arguments) == 0 {
+			return Literal{}, fmt.Errorf("%s: expected at least 1 argument but got 0", name)
+		}
+
+		result, err := numberArgument(name, arguments, 0)
+		if err != nil {
+			return Literal{}, err
+		}
+
+		for j := 1; j < len(arguments); j++ {
+			f, err := numberArgument(name, arguments, j)
+			if err != nil {
+				return Literal{}, err
+			}
+
+			result = fn(result, f)
+		}
+
+		return Literal{Value: result}, nil
+	})
+}
+
+// random returns the random number generator of the interpreter, seeded with
+// the current time unless seed was called.
+func (i *Interpreter) random() *rand.Rand {
+	if i.rand == nil {
+		i.rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+
+	return i.rand
+}
+
+var mathNatives = []Native{
+	mathFunction("sqrt", math.Sqrt),
+	mathFunction("floor", math.Floor),
+	mathFunction("ceil", math.Ceil),
+	mathFunction("round", math.Round),
+	mathFunction("abs", math.Abs),
+	mathFunction("sin", math.Sin),
+	mathFunction("cos", math.Cos),
+	mathFunction("tan", math.Tan),
+	mathFunction("log", math.Log),
+	mathFunction("exp", math.Exp),
+	mathReduce("min", math.Min),
+	mathReduce("max", math.Max),
+	NewNative("random", 0, func(i *Interpreter, arguments []Literal) (Literal, error) {
+		return Literal{Value: i.random().Float64()}, nil
+	}),
+	// randomInt returns an integer between its arguments, both included
+	NewNative("randomInt", 2, func(i *Interpreter, arguments []Literal) (Literal, error) {
+		var limits [2]float64
+
+		for j := range limits {
+			f, err := numberArgument("randomInt", arguments, j)
+			if err != nil {
+				return Literal{}, err
+			}
+
+			if f != math.Trunc(f) || math.IsInf(f, 0) {
+				return Literal{}, fmt.Errorf("randomInt: argument %d: expected integer, got %v", j+1, arguments[j])
+			}
+
+			limits[j] = f
+		}
+
+		if limits[0] > limits[1] {
+			return Literal{}, fmt.Errorf("randomInt: empty range [%v, %v]", arguments[0], arguments[1])
+		}
+
+		// the number of values in the range must fit in an int64
+		if limits[1]-limits[0] >= math.MaxInt64 {
+			return Literal{}, fmt.Errorf("randomInt: range [%v, %v] too large", arguments[0], arguments[1])
+		}
+
+		n := i.random().Int63n(int64(limits[1]-limits[0]) + 1)
+
+		return Literal{Value: limits[0] + float64(n)}, nil
+	}),
+	NewNative("seed", 1, func(i *Interpreter, arguments []Literal) (Literal, error) {
+		f, err := numberArgument("seed", arguments, 0)
+		if err != nil {
+			return Literal{}, err
+		}
+
+		i.rand = rand.New(rand.NewSource(int64(f)))
+
+		return Literal{}, nil
+	}),
+}
@@ -0,0 +1,188 @@
+//  MIT License
+//
+//  Copyright (c) 2019 Marco Pacini
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy
+//  of this software and associated documentation files (the "Software"), to deal
+//  in the Software without restriction, including without limitation the rights
+//  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//  copies of the Software, and to permit persons to whom the Software is
+//  furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all
+//  copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//  SOFTWARE.
+
+package ast
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// Module is the value bound by an import: its members are the top-level
+// declarations of the imported file.
+type Module struct {
+	Name    string
+	Path    string
+	Globals *Environment
+}
+
+func (m *Module) Get(t Token) (Literal, error) {
+	if v, ok := m.Globals.Scope[t.Lexeme]; ok {
+		return v.(Literal), nil
+	}
+
+	return Literal{}, newError(t.Span(), "undefined member '%s' in module %s", t.Lexeme, m.Name)
+}
+
+// Set assigns a member declared by the module.
+func (m *Module) Set(t Token, l Literal) error {
+	if _, ok := m.Globals.Scope[t.Lexeme]; !ok {
+		return newError(t.Span(), "undefined member '%s' in module %s", t.Lexeme, m.Name)
+	}
+
+	m.Globals.Scope[t.Lexeme] = l
+
+	return nil
+}
+
+func (m *Module) String() string {
+	return fmt.Sprintf("<module %s>", m.Name)
+}
+
+// findModule returns the absolute path of the file imported as path by the
+// file from. Relative paths are looked up in the directory of from, or in the
+// working directory if from is empty, then in the directories listed in
+// LOX_PATH. The .lox extension can be omitted.
+func findModule(path string, from string) (string, error) {
+	dirs := []string{""}
+
+	if !filepath.IsAbs(path) {
+		dirs = []string{"."}
+		if from != "" {
+			dirs[0] = filepath.Dir(from)
+		}
+
+		dirs = append(dirs, filepath.SplitList(os.Getenv("LOX_PATH"))...)
+	}
+
+	for _, dir := range dirs {
+		for _, name := range []string{path, path + ".lox"} {
+			candidate := filepath.Join(dir, name)
+
+			if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+				return filepath.Abs(candidate)
+			}
+		}
+	}
+
+	return "", fmt.Errorf("cannot find module \"%s\"", path)
+}
+
+// load runs the module at path, unless it has already been loaded, in its own
+// global environment.
+func (i *Interpreter) load(path string) (*Module, error) {
+	chain := append([]string{i.Path}, i.importing...)
+	for j, p := range chain {
+		if p == path {
+			cycle := make([]string, 0, len(chain)-j+1)
+			for _, p := range append(chain[j:], path) {
+				cycle = append(cycle, filepath.Base(p))
+			}
+
+			return nil, fmt.Errorf("import cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+
+	if m, ok := i.modules[path]; ok {
+		return m, nil
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// errors found before the module runs are reported all together
+	fail := func(err error) (*Module, error) {
+		switch e := err.(type) {
+		case ErrorList:
+			return nil, ModuleError{path, e}
+		case Error:
+			return nil, ModuleError{path, ErrorList{e}}
+		}
+
+		return nil, err
+	}
+
+	scanner := Scanner{Text: string(b)}
+	tokens, err := scanner.Scan()
+	if err != nil {
+		return fail(err)
+	}
+
+	parser := Parser{Tokens: tokens}
+	stmts, err := parser.Parse()
+	if err != nil {
+		return fail(err)
+	}
+
+	var resolver Resolver
+	if err := resolver.Resolve(stmts); err != nil {
+		return fail(err)
+	}
+
+	m := &Module{strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)), path, NewEnvironment(i.Builtins)}
+
+	previous, locals, file := i.Environment, i.Locals, i.file
+	i.importing = append(i.importing, path)
+
+	defer func() {
+		i.Environment, i.Locals, i.file = previous, locals, file
+		i.importing = i.importing[:len(i.importing)-1]
+	}()
+
+	i.Environment, i.Locals, i.file = m.Globals, resolver.Locals, path
+
+	for _, stmt := range stmts {
+		if err := i.execute(stmt); err != nil {
+			// a return outside of a function ends the module
+			if _, ok := err.(ReturnValue); ok {
+				break
+			}
+
+			return nil, locate(err, path)
+		}
+	}
+
+	i.modules[path] = m
+
+	return m, nil
+}
+
+// isIdentifier reports whether name can be used as a variable name.
+func isIdentifier(name string) bool {
+	if _, ok := keywords[name]; ok || name == "" {
+		return false
+	}
+
+	for j, c := range name {
+		if !(c == '_' || unicode.IsLetter(c) || (j > 0 && unicode.IsDigit(c))) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,113 @@
+//  MIT License
+//
+//  Copyright (c) 2019 Marco Pacini
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy
+//  of this software and associated documentation files (the "Software"), to deal
+//  in the Software without restriction, including without limitation the rights
+//  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//  copies of the Software, and to permit persons to whom the Software is
+//  furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all
+//  copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//  SOFTWARE.
+
+package ast
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInterpreter_Import(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"lib/math.lox": "import \"../counter.lox\"; fun square(x) { counter.count = counter.count + 1; return x * x; }",
+		"counter.lox":  "class Counter {} var count = 0;",
+		"a.lox":        "import \"b\";",
+		"b.lox":        "import \"a\";",
+		"bad.lox":      "fun f() { return 1 + nil; }",
+		"broken.lox":   "var = 1;\nvar = 2;",
+		"early.lox":    "var x = 1; return; var y = 2;",
+	}
+
+	for name, source := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ioutil.WriteFile(path, []byte(source), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	table := []struct {
+		in  string
+		out interface{}
+		err string
+	}{
+		{"import m from \"lib/math\"; var a = m.square(3);", 9.0, ""},
+		{"import \"lib/math\"; import \"counter\"; math.square(1); var a = counter.count;", 1.0, ""},
+		{"import \"a\"; var a = 0;", nil, "import cycle: a.lox -> b.lox -> a.lox"},
+		{"import \"none\"; var a = 0;", nil, "cannot find module \"none\""},
+		{"import \"bad\"; bad.f(); var a = 0;", nil, "bad.lox at line 1, column 20: invalid operands for binary +"},
+		{"import \"broken\"; var a = 0;", nil, "broken.lox at line 1, column 5: expected 'IDENTIFIER'"},
+		{"import \"broken\"; var a = 0;", nil, "broken.lox at line 2, column 5: expected 'IDENTIFIER'"},
+		{"import \"early\"; var a = early.x;", 1.0, ""},
+		{"import \"early\"; var a = early.y;", nil, "undefined"},
+	}
+
+	for _, test := range table {
+		t.Run(test.in, func(t *testing.T) {
+			scanner := Scanner{test.in}
+			tokens, err := scanner.Scan()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			parser := Parser{Tokens: tokens}
+			stmts, err := parser.Parse()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			i := NewInterpreter()
+			i.Path = filepath.Join(dir, "main.lox")
+
+			err = i.Run(stmts)
+
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("want error %q, got %v", test.err, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if a := i.Globals.Scope["a"].(Literal).Value; a != test.out {
+				t.Errorf("want %v, got %v", test.out, a)
+			}
+		})
+	}
+}
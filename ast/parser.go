@@ -30,12 +30,32 @@ import (
 type Parser struct {
 	Tokens  []Token
 	current int
+	errors  ErrorList
+}
+
+func (p Parser) error(token Token, message string) error {
+	return Error{token.Span(), message}
+}
+
+// span returns the span going from start to the end of the last consumed token.
+func (p *Parser) span(start Span) Span {
+	end, _ := p.previous()
+	return start.To(end.Span())
 }
 
 func (p Parser) peek() Token {
 	return p.Tokens[p.current]
 }
 
+// peekNext returns the token after the current one.
+func (p Parser) peekNext() Token {
+	if p.current+1 >= len(p.Tokens) {
+		return p.Tokens[len(p.Tokens)-1]
+	}
+
+	return p.Tokens[p.current+1]
+}
+
 func (p *Parser) previous() (Token, bool) {
 	if p.current-1 < 0 {
 		return Token{}, false
@@ -73,7 +93,7 @@ func (p *Parser) consume(t TokenType) (Token, error) {
 	token := p.peek()
 
 	if token.TokenType != t {
-		return Token{}, fmt.Errorf("error at line %d: expected '%v'", token.Line, t.String())
+		return Token{}, p.error(token, fmt.Sprintf("expected '%v'", t.String()))
 	}
 
 	p.advance()
@@ -81,15 +101,56 @@ func (p *Parser) consume(t TokenType) (Token, error) {
 	return token, nil
 }
 
-func (p *Parser) declaration() (Stmt, error) {
+// declaration parses a single declaration. On a syntax error, the error is
+// recorded and the parser discards tokens until the start of the next
+// statement, so that the following errors can be reported too.
+func (p *Parser) declaration() Stmt {
+	var stmt Stmt
+	var err error
+
 	if p.match(Var) {
-		return p.variable()
+		stmt, err = p.variable()
+	} else if p.match(Import) {
+		stmt, err = p.importStmt()
+	} else {
+		stmt, err = p.statement()
 	}
 
-	return p.statement()
+	if err != nil {
+		if e, ok := err.(Error); ok {
+			p.errors = append(p.errors, e)
+		} else {
+			p.errors = append(p.errors, Error{p.peek().Span(), err.Error()})
+		}
+
+		p.synchronize()
+
+		return nil
+	}
+
+	return stmt
+}
+
+func (p *Parser) synchronize() {
+	p.advance()
+
+	for !p.isEnd() {
+		if previous, ok := p.previous(); ok && previous.TokenType == Semicolon {
+			return
+		}
+
+		switch p.peek().TokenType {
+		case Class, Fun, Var, For, If, While, Print, Return, Import, Throw, Try:
+			return
+		}
+
+		p.advance()
+	}
 }
 
 func (p *Parser) variable() (Stmt, error) {
+	keyword, _ := p.previous()
+
 	token, err := p.consume(Identifier)
 	if err != nil {
 		return nil, err
@@ -106,22 +167,63 @@ func (p *Parser) variable() (Stmt, error) {
 		return nil, err
 	}
 
-	return Declaration{token, initializer}, nil
+	return Declaration{token, initializer, p.span(keyword.Span())}, nil
+}
+
+// importStmt parses either 'import "path";' or 'import name from "path";',
+// where 'from' is not reserved outside of imports.
+func (p *Parser) importStmt() (Stmt, error) {
+	keyword, _ := p.previous()
+
+	var name *Token
+	if p.match(Identifier) {
+		token, _ := p.previous()
+		name = &token
+
+		if from := p.peek(); from.TokenType != Identifier || from.Lexeme != "from" {
+			return nil, p.error(from, "expected 'from'")
+		}
+
+		p.advance()
+	}
+
+	path, err := p.consume(String)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.consume(Semicolon); err != nil {
+		return nil, err
+	}
+
+	return ImportStmt{keyword, name, path, p.span(keyword.Span())}, nil
 }
 
 func (p *Parser) statement() (Stmt, error) {
+	start := p.peek().Span()
+
 	if p.match(Class) {
 		token, err := p.consume(Identifier)
 		if err != nil {
 			return nil, err
 		}
 
-		if _, err := p.consume(LeftSquare); err != nil {
+		var superclass *Variable
+		if p.match(Less) {
+			name, err := p.consume(Identifier)
+			if err != nil {
+				return nil, err
+			}
+
+			superclass = &Variable{name, name.Span()}
+		}
+
+		if _, err := p.consume(LeftBrace); err != nil {
 			return nil, err
 		}
 
 		var methods []Function
-		for p.peek().TokenType != RightSquare && !p.isEnd() {
+		for p.peek().TokenType != RightBrace && !p.isEnd() {
 			f, err := p.function()
 			if err != nil {
 				return nil, err
@@ -131,11 +233,25 @@ func (p *Parser) statement() (Stmt, error) {
 			methods = append(methods, m)
 		}
 
-		if _, err := p.consume(RightSquare); err != nil {
+		if _, err := p.consume(RightBrace); err != nil {
 			return nil, err
 		}
 
-		return ClassStmt{token, methods}, nil
+		return ClassStmt{token, superclass, methods, p.span(start)}, nil
+	}
+
+	if p.match(Break, Continue) {
+		keyword, _ := p.previous()
+
+		if _, err := p.consume(Semicolon); err != nil {
+			return nil, err
+		}
+
+		if keyword.TokenType == Break {
+			return BreakStmt{keyword, p.span(start)}, nil
+		}
+
+		return ContinueStmt{keyword, p.span(start)}, nil
 	}
 
 	if p.match(If) {
@@ -164,7 +280,7 @@ func (p *Parser) statement() (Stmt, error) {
 			}
 		}
 
-		return IfStmt{condition, thenBranch, elseBranch}, nil
+		return IfStmt{condition, thenBranch, elseBranch, p.span(start)}, nil
 	}
 
 	if p.match(For) {
@@ -183,12 +299,18 @@ func (p *Parser) statement() (Stmt, error) {
 				return nil, err
 			}
 		} else {
+			initStart := p.peek().Span()
+
 			expr, err := p.expression()
 			if err != nil {
 				return nil, err
 			}
 
-			init = ExprStmt{expr}
+			if _, err := p.consume(Semicolon); err != nil {
+				return nil, err
+			}
+
+			init = ExprStmt{expr, p.span(initStart)}
 		}
 
 		var condition Expr
@@ -222,10 +344,12 @@ func (p *Parser) statement() (Stmt, error) {
 			return nil, err
 		}
 
-		return ForStmt{init, condition, increment, body}, nil
+		return ForStmt{init, condition, increment, body, p.span(start)}, nil
 	}
 
-	if p.match(Fun) {
+	// an anonymous function is parsed as an expression statement
+	if p.peek().TokenType == Fun && p.peekNext().TokenType != LeftParenthesis {
+		p.advance()
 		return p.function()
 	}
 
@@ -239,10 +363,31 @@ func (p *Parser) statement() (Stmt, error) {
 			return nil, err
 		}
 
-		return PrintStmt{expr}, nil
+		return PrintStmt{expr, p.span(start)}, nil
 	}
 
 	if p.match(Return) {
+		keyword, _ := p.previous()
+
+		var expr Expr
+		var err error
+
+		if p.peek().TokenType != Semicolon {
+			if expr, err = p.expression(); err != nil {
+				return nil, err
+			}
+		}
+
+		if _, err := p.consume(Semicolon); err != nil {
+			return nil, err
+		}
+
+		return ReturnStmt{keyword, expr, p.span(start)}, nil
+	}
+
+	if p.match(Throw) {
+		keyword, _ := p.previous()
+
 		expr, err := p.expression()
 		if err != nil {
 			return nil, err
@@ -252,7 +397,11 @@ func (p *Parser) statement() (Stmt, error) {
 			return nil, err
 		}
 
-		return ReturnStmt{expr}, nil
+		return ThrowStmt{keyword, expr, p.span(start)}, nil
+	}
+
+	if p.match(Try) {
+		return p.tryStmt(start)
 	}
 
 	if p.match(While) {
@@ -274,16 +423,16 @@ func (p *Parser) statement() (Stmt, error) {
 			return nil, err
 		}
 
-		return WhileStmt{condition, body}, nil
+		return WhileStmt{condition, body, p.span(start)}, nil
 	}
 
-	if p.match(LeftSquare) {
+	if p.match(LeftBrace) {
 		b, err := p.block()
 		if err != nil {
 			return nil, err
 		}
 
-		return Block{b}, nil
+		return Block{b, p.span(start)}, nil
 	}
 
 	expr, err := p.expression()
@@ -295,15 +444,140 @@ func (p *Parser) statement() (Stmt, error) {
 		return nil, err
 	}
 
-	return ExprStmt{expr}, nil
+	return ExprStmt{expr, p.span(start)}, nil
+}
+
+func (p *Parser) tryStmt(start Span) (Stmt, error) {
+	// unlike other statements, the clauses of a try statement must be blocks
+	braced := func() (Block, error) {
+		blockStart := p.peek().Span()
+
+		if _, err := p.consume(LeftBrace); err != nil {
+			return Block{}, err
+		}
+
+		stmts, err := p.block()
+		if err != nil {
+			return Block{}, err
+		}
+
+		return Block{stmts, p.span(blockStart)}, nil
+	}
+
+	body, err := braced()
+	if err != nil {
+		return nil, err
+	}
+
+	var name Token
+	var catch, finally *Block
+
+	if p.match(Catch) {
+		if _, err := p.consume(LeftParenthesis); err != nil {
+			return nil, err
+		}
+
+		if name, err = p.consume(Identifier); err != nil {
+			return nil, err
+		}
+
+		if _, err := p.consume(RightParenthesis); err != nil {
+			return nil, err
+		}
+
+		b, err := braced()
+		if err != nil {
+			return nil, err
+		}
+
+		catch = &b
+	}
+
+	if p.match(Finally) {
+		b, err := braced()
+		if err != nil {
+			return nil, err
+		}
+
+		finally = &b
+	}
+
+	if catch == nil && finally == nil {
+		return nil, p.error(p.peek(), "expected 'catch' or 'finally' after try block")
+	}
+
+	return TryStmt{body, name, catch, finally, p.span(start)}, nil
 }
 
 func (p *Parser) function() (Stmt, error) {
+	start := p.peek().Span()
+	if keyword, ok := p.previous(); ok && keyword.TokenType == Fun {
+		start = keyword.Span()
+	}
+
 	name, err := p.consume(Identifier)
 	if err != nil {
 		return nil, err
 	}
 
+	arguments, err := p.parameters()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.consume(LeftBrace); err != nil {
+		return nil, err
+	}
+
+	body, err := p.block()
+	if err != nil {
+		return nil, err
+	}
+
+	return Function{name, nil, nil, "", arguments, body, false, p.span(start)}, nil
+}
+
+// lambda parses an anonymous function, after its fun keyword. The body is
+// either a block or, after an arrow, a single expression whose value is
+// returned.
+func (p *Parser) lambda() (Expr, error) {
+	keyword, _ := p.previous()
+	start := keyword.Span()
+
+	arguments, err := p.parameters()
+	if err != nil {
+		return nil, err
+	}
+
+	var body []Stmt
+
+	if p.match(Arrow) {
+		arrow, _ := p.previous()
+		exprStart := p.peek().Span()
+
+		expr, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+
+		body = []Stmt{ReturnStmt{arrow, expr, p.span(exprStart)}}
+	} else {
+		if _, err := p.consume(LeftBrace); err != nil {
+			return nil, err
+		}
+
+		if body, err = p.block(); err != nil {
+			return nil, err
+		}
+	}
+
+	span := p.span(start)
+
+	return Lambda{Function{keyword, nil, nil, "", arguments, body, false, span}, span}, nil
+}
+
+// parameters parses the parenthesized parameter list of a function.
+func (p *Parser) parameters() ([]Token, error) {
 	if _, err := p.consume(LeftParenthesis); err != nil {
 		return nil, err
 	}
@@ -328,31 +602,19 @@ func (p *Parser) function() (Stmt, error) {
 		return nil, err
 	}
 
-	if _, err := p.consume(LeftSquare); err != nil {
-		return nil, err
-	}
-
-	body, err := p.block()
-	if err != nil {
-		return nil, err
-	}
-
-	return Function{name, nil, arguments, body}, nil
+	return arguments, nil
 }
 
 func (p *Parser) block() ([]Stmt, error) {
 	var stmts []Stmt
 
-	for !(p.peek().TokenType == RightSquare) && !p.isEnd() {
-		stmt, err := p.declaration()
-		if err != nil {
-			return nil, err
+	for !(p.peek().TokenType == RightBrace) && !p.isEnd() {
+		if stmt := p.declaration(); stmt != nil {
+			stmts = append(stmts, stmt)
 		}
-
-		stmts = append(stmts, stmt)
 	}
 
-	if _, err := p.consume(RightSquare); err != nil {
+	if _, err := p.consume(RightBrace); err != nil {
 		return nil, err
 	}
 
@@ -364,6 +626,8 @@ func (p *Parser) expression() (Expr, error) {
 }
 
 func (p *Parser) assignment() (Expr, error) {
+	start := p.peek().Span()
+
 	expr, err := p.or()
 	if err != nil {
 		return nil, err
@@ -377,12 +641,14 @@ func (p *Parser) assignment() (Expr, error) {
 			}
 
 			if v, ok := expr.(Variable); ok {
-				return Assign{v, t, value}, nil
+				return Assign{v, t, value, p.span(start)}, nil
 			} else if g, ok := expr.(Get); ok {
-				return Set{g.Object, g.Name, value}, nil
+				return Set{g.Object, g.Name, value, p.span(start)}, nil
+			} else if i, ok := expr.(Index); ok {
+				return IndexSet{i.Object, i.Bracket, i.Index, value, p.span(start)}, nil
 			}
 
-			return nil, fmt.Errorf("error at line %d: invalid assignment target", t.Line)
+			return nil, p.error(t, "invalid assignment target")
 		}
 	}
 
@@ -390,6 +656,8 @@ func (p *Parser) assignment() (Expr, error) {
 }
 
 func (p *Parser) or() (Expr, error) {
+	start := p.peek().Span()
+
 	expr, err := p.and()
 	if err != nil {
 		return nil, err
@@ -402,7 +670,7 @@ func (p *Parser) or() (Expr, error) {
 				return nil, err
 			}
 
-			expr = Logical{expr, operator, right}
+			expr = Logical{expr, operator, right, p.span(start)}
 		}
 	}
 
@@ -410,6 +678,8 @@ func (p *Parser) or() (Expr, error) {
 }
 
 func (p *Parser) and() (Expr, error) {
+	start := p.peek().Span()
+
 	expr, err := p.equality()
 	if err != nil {
 		return nil, err
@@ -422,7 +692,7 @@ func (p *Parser) and() (Expr, error) {
 				return nil, err
 			}
 
-			expr = Logical{expr, operator, right}
+			expr = Logical{expr, operator, right, p.span(start)}
 		}
 	}
 
@@ -430,6 +700,8 @@ func (p *Parser) and() (Expr, error) {
 }
 
 func (p *Parser) equality() (Expr, error) {
+	start := p.peek().Span()
+
 	expr, err := p.comparison()
 	if err != nil {
 		return nil, err
@@ -442,7 +714,7 @@ func (p *Parser) equality() (Expr, error) {
 				return nil, err
 			}
 
-			expr = Binary{expr, operator, right}
+			expr = Binary{expr, operator, right, p.span(start)}
 		}
 	}
 
@@ -450,6 +722,8 @@ func (p *Parser) equality() (Expr, error) {
 }
 
 func (p *Parser) comparison() (Expr, error) {
+	start := p.peek().Span()
+
 	expr, err := p.addition()
 	if err != nil {
 		return nil, err
@@ -462,7 +736,7 @@ func (p *Parser) comparison() (Expr, error) {
 				return nil, err
 			}
 
-			expr = Binary{expr, operator, right}
+			expr = Binary{expr, operator, right, p.span(start)}
 		}
 	}
 
@@ -470,6 +744,8 @@ func (p *Parser) comparison() (Expr, error) {
 }
 
 func (p *Parser) addition() (Expr, error) {
+	start := p.peek().Span()
+
 	expr, err := p.multiplication()
 	if err != nil {
 		return nil, err
@@ -482,7 +758,7 @@ func (p *Parser) addition() (Expr, error) {
 				return nil, err
 			}
 
-			expr = Binary{expr, operator, right}
+			expr = Binary{expr, operator, right, p.span(start)}
 		}
 	}
 
@@ -490,19 +766,21 @@ func (p *Parser) addition() (Expr, error) {
 }
 
 func (p *Parser) multiplication() (Expr, error) {
+	start := p.peek().Span()
+
 	expr, err := p.unary()
 	if err != nil {
 		return nil, err
 	}
 
-	for p.match(Slash, Star) {
+	for p.match(Slash, Star, Percent) {
 		if operator, ok := p.previous(); ok {
 			right, err := p.unary()
 			if err != nil {
 				return nil, err
 			}
 
-			expr = Binary{expr, operator, right}
+			expr = Binary{expr, operator, right, p.span(start)}
 		}
 	}
 
@@ -517,14 +795,40 @@ func (p *Parser) unary() (Expr, error) {
 				return nil, err
 			}
 
-			return Unary{operator, right}, nil
+			return Unary{operator, right, p.span(operator.Span())}, nil
+		}
+	}
+
+	return p.power()
+}
+
+// power parses exponentiation, which binds tighter than unary operators on its
+// left and is right-associative: -2 ** 2 is -4 and 2 ** 3 ** 2 is 512.
+func (p *Parser) power() (Expr, error) {
+	start := p.peek().Span()
+
+	expr, err := p.call()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.match(StarStar) {
+		if operator, ok := p.previous(); ok {
+			right, err := p.unary()
+			if err != nil {
+				return nil, err
+			}
+
+			return Binary{expr, operator, right, p.span(start)}, nil
 		}
 	}
 
-	return p.call()
+	return expr, nil
 }
 
 func (p *Parser) call() (Expr, error) {
+	start := p.peek().Span()
+
 	expr, err := p.primary()
 	if err != nil {
 		return nil, err
@@ -552,14 +856,18 @@ func (p *Parser) call() (Expr, error) {
 				return nil, err
 			}
 
-			return Call{expr, arguments}, nil
+			expr = Call{expr, arguments, p.span(start)}
 		} else if p.match(Dot) {
 			property, err := p.consume(Identifier)
 			if err != nil {
 				return nil, err
 			}
 
-			return Get{property, expr}, nil
+			expr = Get{property, expr, p.span(start)}
+		} else if p.match(LeftBracket) {
+			if expr, err = p.index(expr, start); err != nil {
+				return nil, err
+			}
 		} else {
 			break
 		}
@@ -568,39 +876,100 @@ func (p *Parser) call() (Expr, error) {
 	return expr, nil
 }
 
+// index parses what follows the '[' after object: either an index or a slice.
+func (p *Parser) index(object Expr, start Span) (Expr, error) {
+	bracket, _ := p.previous()
+
+	var first, second Expr
+	var err error
+
+	if p.peek().TokenType != Colon {
+		if first, err = p.expression(); err != nil {
+			return nil, err
+		}
+	}
+
+	if !p.match(Colon) {
+		if _, err := p.consume(RightBracket); err != nil {
+			return nil, err
+		}
+
+		return Index{object, bracket, first, p.span(start)}, nil
+	}
+
+	if p.peek().TokenType != RightBracket {
+		if second, err = p.expression(); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := p.consume(RightBracket); err != nil {
+		return nil, err
+	}
+
+	return Slice{object, bracket, first, second, p.span(start)}, nil
+}
+
 func (p *Parser) primary() (Expr, error) {
+	start := p.peek().Span()
+
 	if p.match(True) {
-		return Literal{true}, nil
+		return Literal{true, start}, nil
 	}
 
 	if p.match(False) {
-		return Literal{false}, nil
+		return Literal{false, start}, nil
 	}
 
 	if p.match(Nil) {
-		return Literal{nil}, nil
+		return Literal{nil, start}, nil
+	}
+
+	if p.match(Fun) {
+		return p.lambda()
 	}
 
 	if p.match(Number) {
 		if token, ok := p.previous(); ok {
 			value, err := strconv.ParseFloat(token.Literal, 64)
 			if err != nil {
-				return nil, err
+				return nil, p.error(token, fmt.Sprintf("invalid number '%s'", token.Lexeme))
 			}
 
-			return Literal{value}, nil
+			return Literal{value, start}, nil
 		}
 	}
 
 	if p.match(String) {
 		if token, ok := p.previous(); ok {
-			return Literal{token.Literal}, nil
+			return Literal{token.Literal, start}, nil
+		}
+	}
+
+	if p.match(Super) {
+		if keyword, ok := p.previous(); ok {
+			if _, err := p.consume(Dot); err != nil {
+				return nil, err
+			}
+
+			method, err := p.consume(Identifier)
+			if err != nil {
+				return nil, err
+			}
+
+			return SuperExpr{keyword, method, p.span(start)}, nil
+		}
+	}
+
+	if p.match(This) {
+		if keyword, ok := p.previous(); ok {
+			return ThisExpr{keyword, start}, nil
 		}
 	}
 
 	if p.match(Identifier) {
 		if token, ok := p.previous(); ok {
-			return Variable{token}, nil
+			return Variable{token, start}, nil
 		}
 	}
 
@@ -614,22 +983,103 @@ func (p *Parser) primary() (Expr, error) {
 			return nil, err
 		}
 
-		return Grouping{expr}, nil
+		return Grouping{expr, p.span(start)}, nil
 	}
 
-	return nil, fmt.Errorf("error at line %d: unknown token '%s'", p.peek().Line, p.peek().Literal)
+	if p.match(LeftBracket) {
+		var elements []Expr
+
+		for p.peek().TokenType != RightBracket && !p.isEnd() {
+			element, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+
+			elements = append(elements, element)
+
+			if !p.match(Comma) {
+				break
+			}
+		}
+
+		if _, err := p.consume(RightBracket); err != nil {
+			return nil, err
+		}
+
+		return ListExpr{elements, p.span(start)}, nil
+	}
+
+	// a brace starting a statement opens a block, everywhere else a map
+	if p.match(LeftBrace) {
+		var keys, values []Expr
+
+		for p.peek().TokenType != RightBrace && !p.isEnd() {
+			key, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+
+			if _, err := p.consume(Colon); err != nil {
+				return nil, err
+			}
+
+			value, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+
+			keys = append(keys, key)
+			values = append(values, value)
+
+			if !p.match(Comma) {
+				break
+			}
+		}
+
+		if _, err := p.consume(RightBrace); err != nil {
+			return nil, err
+		}
+
+		return MapExpr{keys, values, p.span(start)}, nil
+	}
+
+	if p.isEnd() {
+		return nil, p.error(p.peek(), "unexpected end of file")
+	}
+
+	return nil, p.error(p.peek(), fmt.Sprintf("unknown token '%s'", p.peek().Lexeme))
 }
 
+// Parse returns the statements parsed from the tokens. If any syntax error is
+// found, the returned error is an ErrorList with all of them, and the
+// statements are the ones that could be parsed.
 func (p *Parser) Parse() ([]Stmt, error) {
 	var stmts []Stmt
 
 	for !p.isEnd() {
-		if stmt, err := p.declaration(); err != nil {
-			return nil, err
-		} else {
+		if stmt := p.declaration(); stmt != nil {
 			stmts = append(stmts, stmt)
 		}
 	}
 
+	if len(p.errors) > 0 {
+		return stmts, p.errors
+	}
+
 	return stmts, nil
 }
+
+// ParseExpression parses tokens holding a single expression, as typed at the
+// prompt of a debugger.
+func (p *Parser) ParseExpression() (Expr, error) {
+	expr, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.isEnd() {
+		return nil, p.error(p.peek(), "expected end of expression")
+	}
+
+	return expr, nil
+}
@@ -0,0 +1,65 @@
+//  MIT License
+//
+//  Copyright (c) 2019 Marco Pacini
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy
+//  of this software and associated documentation files (the "Software"), to deal
+//  in the Software without restriction, including without limitation the rights
+//  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//  copies of the Software, and to permit persons to whom the Software is
+//  furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all
+//  copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//  SOFTWARE.
+
+package ast
+
+import "testing"
+
+func TestParser_ParseErrors(t *testing.T) {
+	table := []struct {
+		in  string
+		out []Error
+	}{
+		{"print 1;", nil},
+		{"var a = ;\nprint a\nvar b = 2;", []Error{{Span{1, 9, 8, 1}, "unknown token ';'"}, {Span{3, 1, 18, 3}, "expected 'SEMICOLON'"}}},
+		{"fun f() {\n  var c = +;\n  print c;\n}\nclass { }", []Error{{Span{2, 11, 20, 1}, "unknown token '+'"}, {Span{5, 7, 42, 1}, "expected 'IDENTIFIER'"}}},
+		{"1 = 2;\nprint", []Error{{Span{1, 3, 2, 1}, "invalid assignment target"}, {Span{2, 6, 12, 0}, "unexpected end of file"}}},
+	}
+
+	for _, test := range table {
+		t.Run(test.in, func(t *testing.T) {
+			scanner := Scanner{test.in}
+			tokens, err := scanner.Scan()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			parser := Parser{Tokens: tokens}
+			_, err = parser.Parse()
+
+			var errors ErrorList
+			if err != nil {
+				errors = err.(ErrorList)
+			}
+
+			if len(errors) != len(test.out) {
+				t.Fatalf("want %d errors, got %d: %v", len(test.out), len(errors), errors)
+			}
+
+			for i := range test.out {
+				if errors[i] != test.out[i] {
+					t.Errorf("want %v, got %v", test.out[i], errors[i])
+				}
+			}
+		})
+	}
+}
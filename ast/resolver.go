@@ -22,8 +22,6 @@
 
 package ast
 
-import "fmt"
-
 type Scope map[string]bool
 
 func NewScope() Scope {
@@ -75,15 +73,62 @@ func (s *Stack) Define(name string) {
 	}
 }
 
+type classType int
+
+const (
+	noClass classType = iota
+	plainClass
+	subClass
+)
+
+type functionType int
+
+const (
+	noFunction functionType = iota
+	plainFunction
+	method
+	initializer
+)
+
 type Resolver struct {
 	Stack
-	Locals map[string]int
+	Locals map[int]int
+	// Symbols, if set, collects the declarations and their references.
+	Symbols  *Symbols
+	class    classType
+	function functionType
+	loops    int
 }
 
+// localKey identifies a variable reference by the offset of its token, which
+// is unique in the source.
+func localKey(t Token) int {
+	return t.Offset
+}
+
+// Resolve computes the scope distance of the variables used in stmts. The
+// global scope is kept across calls, so a resolver can be reused to resolve
+// programs that run one after the other on the same interpreter.
 func (r *Resolver) Resolve(stmts []Stmt) error {
-	r.Stack = NewStack()
-	r.Stack.Push(NewScope())
-	r.Locals = make(map[string]int, 0)
+	if len(r.stack) == 0 {
+		r.Stack.Push(NewScope())
+	}
+
+	if r.Symbols != nil {
+		r.Symbols.stack = []*SymbolScope{r.Symbols.Global}
+	}
+
+	defer func() {
+		// an error can leave inner scopes open
+		r.stack = r.stack[:1]
+		r.class, r.function, r.loops = noClass, noFunction, 0
+
+		if r.Symbols != nil {
+			r.Symbols.resolve()
+		}
+	}()
+
+	r.Locals = make(map[int]int, 0)
 
 	for _, stmt := range stmts {
 		if err := stmt.Accept(r); err != nil {
@@ -94,12 +139,29 @@ func (r *Resolver) Resolve(stmts []Stmt) error {
 	return nil
 }
 
-func (r *Resolver) beginScope() {
+func (r *Resolver) beginScope(span Span) {
 	r.Stack.Push(NewScope())
+
+	if r.Symbols != nil {
+		r.Symbols.begin(span)
+	}
 }
 
 func (r *Resolver) endScope() {
 	r.Stack.Pop()
+
+	if r.Symbols != nil {
+		r.Symbols.end()
+	}
+}
+
+// symbol records the symbol declared by name, if symbols are collected.
+func (r *Resolver) symbol(name Token, kind SymbolKind, span Span) *Symbol {
+	if r.Symbols == nil {
+		return nil
+	}
+
+	return r.Symbols.declare(name, kind, span)
 }
 
 func (r *Resolver) visitAssign(a Assign) error {
@@ -127,7 +189,7 @@ func (r *Resolver) visitBinary(b Binary) error {
 }
 
 func (r *Resolver) visitBlock(b Block) error {
-	r.beginScope()
+	r.beginScope(b.Span)
 	for _, stmt := range b.Stmts {
 		if err := stmt.Accept(r); err != nil {
 			return err
@@ -138,9 +200,17 @@ func (r *Resolver) visitBlock(b Block) error {
 	return nil
 }
 
+func (r *Resolver) visitBreakStmt(b BreakStmt) error {
+	if r.loops == 0 {
+		return newError(b.Keyword.Span(), "cannot use 'break' outside of a loop")
+	}
+
+	return nil
+}
+
 func (r *Resolver) visitCall(c Call) error {
 	if err := c.Callee.Accept(r); err != nil {
-		return nil
+		return err
 	}
 
 	for _, expr := range c.Arguments {
@@ -153,8 +223,69 @@ func (r *Resolver) visitCall(c Call) error {
 }
 
 func (r *Resolver) visitClassStmt(c ClassStmt) error {
+	enclosing := r.class
+	r.class = plainClass
+
+	defer func() {
+		r.class = enclosing
+	}()
+
 	r.Stack.Declare(c.Name.Lexeme)
 	r.Stack.Define(c.Name.Lexeme)
+
+	if class := r.symbol(c.Name, ClassSymbol, c.Span); class != nil {
+		for _, m := range c.Methods {
+			if m.Name.Lexeme == "init" {
+				class.Parameters = parameters(m)
+			}
+
+			class.Methods = append(class.Methods, r.Symbols.method(m))
+		}
+	}
+
+	if c.Superclass != nil {
+		if c.Superclass.Lexeme == c.Name.Lexeme {
+			return newError(c.Superclass.Span, "a class cannot inherit from itself")
+		}
+
+		r.class = subClass
+
+		if err := c.Superclass.Accept(r); err != nil {
+			return err
+		}
+
+		r.beginScope(c.Span)
+		r.Stack.Define("super")
+	}
+
+	r.beginScope(c.Span)
+	r.Stack.Define("this")
+
+	for _, m := range c.Methods {
+		kind := method
+		if m.Name.Lexeme == "init" {
+			kind = initializer
+		}
+
+		if err := r.resolveFunction(m, kind); err != nil {
+			return err
+		}
+	}
+
+	r.endScope()
+
+	if c.Superclass != nil {
+		r.endScope()
+	}
+
+	return nil
+}
+
+func (r *Resolver) visitContinueStmt(c ContinueStmt) error {
+	if r.loops == 0 {
+		return newError(c.Keyword.Span(), "cannot use 'continue' outside of a loop")
+	}
+
 	return nil
 }
 
@@ -166,6 +297,7 @@ func (r *Resolver) visitDeclaration(d Declaration) error {
 		}
 	}
 	r.Stack.Define(d.Lexeme)
+	r.symbol(d.Token, VariableSymbol, d.Span)
 
 	return nil
 }
@@ -197,18 +329,33 @@ func (r *Resolver) visitForStmt(f ForStmt) error {
 		}
 	}
 
-	if err := f.Body.Accept(r); err != nil {
-		return err
+	return r.resolveLoop(f.Body)
+}
+
+func (r *Resolver) visitFunction(f Function) error {
+	r.Stack.Declare(f.Name.Lexeme)
+	r.Stack.Define(f.Name.Lexeme)
+
+	if sym := r.symbol(f.Name, FunctionSymbol, f.Span); sym != nil {
+		sym.Parameters = parameters(f)
 	}
 
-	return nil
+	return r.resolveFunction(f, plainFunction)
 }
 
-func (r *Resolver) visitFunction(f Function) error {
-	r.beginScope()
+func (r *Resolver) resolveFunction(f Function, kind functionType) error {
+	enclosing, loops := r.function, r.loops
+	r.function, r.loops = kind, 0
+
+	defer func() {
+		r.function, r.loops = enclosing, loops
+	}()
+
+	r.beginScope(f.Span)
 	for _, argument := range f.Arguments {
 		r.Stack.Declare(argument.Lexeme)
 		r.Stack.Define(argument.Lexeme)
+		r.symbol(argument, ParameterSymbol, argument.Span())
 	}
 
 	for _, stmt := range f.Body {
@@ -251,6 +398,59 @@ func (r *Resolver) visitIfStmt(i IfStmt) error {
 	return nil
 }
 
+func (r *Resolver) visitImportStmt(i ImportStmt) error {
+	if r.function != noFunction || len(r.stack) > 1 {
+		return newError(i.Keyword.Span(), "imports are only allowed at top level")
+	}
+
+	r.Stack.Declare(i.name())
+	r.Stack.Define(i.name())
+
+	name := i.Path
+	if i.Name != nil {
+		name = *i.Name
+	}
+	name.Lexeme = i.name()
+
+	r.symbol(name, ModuleSymbol, i.Span)
+
+	return nil
+}
+
+func (r *Resolver) visitIndex(i Index) error {
+	if err := i.Object.Accept(r); err != nil {
+		return err
+	}
+
+	return i.Index.Accept(r)
+}
+
+func (r *Resolver) visitIndexSet(i IndexSet) error {
+	if err := i.Object.Accept(r); err != nil {
+		return err
+	}
+
+	if err := i.Index.Accept(r); err != nil {
+		return err
+	}
+
+	return i.Value.Accept(r)
+}
+
+func (r *Resolver) visitLambda(l Lambda) error {
+	return r.resolveFunction(l.Function, plainFunction)
+}
+
+func (r *Resolver) visitListExpr(l ListExpr) error {
+	for _, element := range l.Elements {
+		if err := element.Accept(r); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (r *Resolver) visitLiteral(l Literal) error {
 	return nil
 }
@@ -267,6 +467,20 @@ func (r *Resolver) visitLogical(l Logical) error {
 	return nil
 }
 
+func (r *Resolver) visitMapExpr(m MapExpr) error {
+	for j := range m.Keys {
+		if err := m.Keys[j].Accept(r); err != nil {
+			return err
+		}
+
+		if err := m.Values[j].Accept(r); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (r *Resolver) visitPrintStmt(p PrintStmt) error {
 	if err := p.Expr.Accept(r); err != nil {
 		return err
@@ -276,6 +490,14 @@ func (r *Resolver) visitPrintStmt(p PrintStmt) error {
 }
 
 func (r *Resolver) visitReturnStmt(s ReturnStmt) error {
+	if s.Expr == nil {
+		return nil
+	}
+
+	if r.function == initializer {
+		return newError(s.Span, "cannot return a value from an initializer")
+	}
+
 	if err := s.Expr.Accept(r); err != nil {
 		return err
 	}
@@ -285,12 +507,80 @@ func (r *Resolver) visitReturnStmt(s ReturnStmt) error {
 
 func (r *Resolver) visitSet(s Set) error {
 	if err := s.Object.Accept(r); err != nil {
-		return nil
+		return err
 	}
 
 	return s.Value.Accept(r)
 }
 
+func (r *Resolver) visitSlice(s Slice) error {
+	if err := s.Object.Accept(r); err != nil {
+		return err
+	}
+
+	for _, bound := range []Expr{s.Start, s.End} {
+		if bound != nil {
+			if err := bound.Accept(r); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (r *Resolver) visitSuperExpr(s SuperExpr) error {
+	switch r.class {
+	case noClass:
+		return newError(s.Keyword.Span(), "cannot use 'super' outside of a class")
+	case plainClass:
+		return newError(s.Keyword.Span(), "cannot use 'super' in a class with no superclass")
+	}
+
+	r.resolveLocal(s.Keyword)
+
+	return nil
+}
+
+func (r *Resolver) visitThisExpr(t ThisExpr) error {
+	if r.class == noClass {
+		return newError(t.Keyword.Span(), "cannot use 'this' outside of a class")
+	}
+
+	r.resolveLocal(t.Keyword)
+
+	return nil
+}
+
+func (r *Resolver) visitThrowStmt(t ThrowStmt) error {
+	return t.Expr.Accept(r)
+}
+
+func (r *Resolver) visitTryStmt(t TryStmt) error {
+	if err := t.Body.Accept(r); err != nil {
+		return err
+	}
+
+	if t.Catch != nil {
+		r.beginScope(t.Catch.Span)
+		r.Stack.Declare(t.Name.Lexeme)
+		r.Stack.Define(t.Name.Lexeme)
+		r.symbol(t.Name, VariableSymbol, t.Name.Span())
+
+		if err := t.Catch.Accept(r); err != nil {
+			return err
+		}
+
+		r.endScope()
+	}
+
+	if t.Finally != nil {
+		return t.Finally.Accept(r)
+	}
+
+	return nil
+}
+
 func (r *Resolver) visitUnary(u Unary) error {
 	if err := u.Right.Accept(r); err != nil {
 		return err
@@ -300,20 +590,34 @@ func (r *Resolver) visitUnary(u Unary) error {
 }
 
 func (r *Resolver) visitVariable(v Variable) error {
-	if s, ok := r.Stack.Head(); ok {
+	// globals can be redefined in terms of themselves
+	if s, ok := r.Stack.Head(); ok && len(r.stack) > 1 {
 		if b, ok := s[v.Lexeme]; ok && !b {
-			return fmt.Errorf("error at line %d: cannot read local variable in its own initializer\n", v.Line)
+			return newError(v.Span, "cannot read local variable in its own initializer")
 		}
 	}
 
+	r.resolveLocal(v.Token)
+
+	return nil
+}
+
+func (r *Resolver) resolveLocal(t Token) {
 	for i := len(r.stack) - 1; i >= 0; i-- {
-		if _, ok := r.stack[i][v.Lexeme]; ok {
-			r.Locals[fmt.Sprintf("%v%v", v.Lexeme, v.Line)] = len(r.stack) - 1 - i
-			break
+		if _, ok := r.stack[i][t.Lexeme]; ok {
+			r.Locals[localKey(t)] = len(r.stack) - 1 - i
+
+			if r.Symbols != nil {
+				r.Symbols.refer(t, i)
+			}
+
+			return
 		}
 	}
 
-	return nil
+	if r.Symbols != nil {
+		r.Symbols.unresolved = append(r.Symbols.unresolved, t)
+	}
 }
 
 func (r *Resolver) visitWhileStmt(w WhileStmt) error {
@@ -321,9 +625,14 @@ func (r *Resolver) visitWhileStmt(w WhileStmt) error {
 		return err
 	}
 
-	if err := w.Body.Accept(r); err != nil {
-		return err
-	}
+	return r.resolveLoop(w.Body)
+}
 
-	return nil
+func (r *Resolver) resolveLoop(body Stmt) error {
+	r.loops++
+	defer func() {
+		r.loops--
+	}()
+
+	return body.Accept(r)
 }
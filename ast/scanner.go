@@ -31,11 +31,27 @@ type Scanner struct {
 func (s *Scanner) Scan() ([]Token, error) {
 	runes := []rune(s.Text)
 
+	// byte offset of each rune, plus the length of the text
+	offsets := make([]int, 0, len(runes)+1)
+	for offset := range s.Text {
+		offsets = append(offsets, offset)
+	}
+	offsets = append(offsets, len(s.Text))
+
 	start := 0
 	current := 0
 	line := 1
+	lineStart := 0
+
+	// position of the first character of the current lexeme
+	startLine := 1
+	startColumn := 1
 
 	tokens := make([]Token, 0)
+	errors := make(ErrorList, 0)
+
+	// comments waiting for the next token
+	var comments []Comment
 
 	isEnd := func() bool {
 		return current >= len(runes)
@@ -81,18 +97,32 @@ func (s *Scanner) Scan() ([]Token, error) {
 	}
 
 	isLetter := func(r rune) bool {
-		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || r == '_' {
 			return true
 		}
 
 		return false
 	}
 
+	span := func() Span {
+		return Span{startLine, startColumn, offsets[start], offsets[current] - offsets[start]}
+	}
+
+	addLiteral := func(tokenType TokenType, literal string) {
+		sp := span()
+		tokens = append(tokens, Token{tokenType, string(runes[start:current]), literal, sp.Line, sp.Column, sp.Offset, sp.Length, comments})
+		comments = nil
+	}
+
 	addToken := func(tokenType TokenType) {
-		tokens = append(tokens, Token{tokenType, string(runes[start:current]), "", line})
+		addLiteral(tokenType, "")
+	}
+
+	addError := func(message string) {
+		errors = append(errors, Error{span(), message})
 	}
 
-	scanToken := func() error {
+	scanToken := func() {
 		r := advance()
 
 		switch r {
@@ -106,10 +136,11 @@ func (s *Scanner) Scan() ([]Token, error) {
 		case '\n':
 			{
 				line++
+				lineStart = current
 				break
 			}
 
-		// Single-character lexeme: '(', ')', '[', ']', '.', '-', '+', ';'
+		// Single-character lexeme: '(', ')', '{', '}', '[', ']', ':', '.', '-', '+', '%', ';'
 		case '(':
 			{
 				addToken(LeftParenthesis)
@@ -124,13 +155,31 @@ func (s *Scanner) Scan() ([]Token, error) {
 
 		case '{':
 			{
-				addToken(LeftSquare)
+				addToken(LeftBrace)
 				break
 			}
 
 		case '}':
 			{
-				addToken(RightSquare)
+				addToken(RightBrace)
+				break
+			}
+
+		case '[':
+			{
+				addToken(LeftBracket)
+				break
+			}
+
+		case ']':
+			{
+				addToken(RightBracket)
+				break
+			}
+
+		case ':':
+			{
+				addToken(Colon)
 				break
 			}
 
@@ -152,9 +201,9 @@ func (s *Scanner) Scan() ([]Token, error) {
 				break
 			}
 
-		case '*':
+		case '%':
 			{
-				addToken(Star)
+				addToken(Percent)
 				break
 			}
 
@@ -170,7 +219,18 @@ func (s *Scanner) Scan() ([]Token, error) {
 				break
 			}
 
-		// Multi-character lexeme (potentially): '/', '!', '=', '<', '>', '!=', '==', '<=', '>=', '//'
+		// Multi-character lexeme (potentially): '/', '!', '=', '<', '>', '*', '!=', '==', '<=', '>=', '**', '//'
+		case '*':
+			{
+				if isNext('*') {
+					addToken(StarStar)
+				} else {
+					addToken(Star)
+				}
+
+				break
+			}
+
 		case '!':
 			{
 				if isNext('=') {
@@ -186,6 +246,8 @@ func (s *Scanner) Scan() ([]Token, error) {
 			{
 				if isNext('=') {
 					addToken(EqualEqual)
+				} else if isNext('>') {
+					addToken(Arrow)
 				} else {
 					addToken(Equal)
 				}
@@ -219,6 +281,8 @@ func (s *Scanner) Scan() ([]Token, error) {
 					for peek() != '\n' && !isEnd() {
 						advance()
 					}
+
+					comments = append(comments, Comment{string(runes[start:current]), span()})
 				} else {
 					addToken(Slash)
 				}
@@ -229,6 +293,7 @@ func (s *Scanner) Scan() ([]Token, error) {
 				for peek() != '"' && !isEnd() {
 					if peek() == '\n' {
 						line++
+						lineStart = current + 1
 					}
 
 					advance()
@@ -236,15 +301,13 @@ func (s *Scanner) Scan() ([]Token, error) {
 
 				// unterminated string
 				if isEnd() {
-					return fmt.Errorf("error at line %d: unterminated string", line)
+					addError("unterminated string")
+					break
 				}
 
 				advance()
 
-				lexeme := string(runes[start:current])
-				literal := string(runes[start+1 : current-1]) // remove double quotes
-
-				tokens = append(tokens, Token{String, lexeme, literal, line})
+				addLiteral(String, string(runes[start+1:current-1])) // remove double quotes
 			}
 
 		default:
@@ -262,36 +325,37 @@ func (s *Scanner) Scan() ([]Token, error) {
 						}
 					}
 
-					number := string(runes[start:current])
-					tokens = append(tokens, Token{Number, number, number, line})
+					addLiteral(Number, string(runes[start:current]))
 				} else if isLetter(r) {
 					for isLetter(peek()) || isDigit(peek()) {
 						advance()
 					}
 
 					if t, ok := keywords[string(runes[start:current])]; ok {
-						tokens = append(tokens, Token{t, string(runes[start:current]), "", line})
+						addToken(t)
 					} else {
-						tokens = append(tokens, Token{Identifier, string(runes[start:current]), "", line})
+						addToken(Identifier)
 					}
 				} else {
-					return fmt.Errorf("unknown character '%v' at line %d", string(r), line)
+					addError(fmt.Sprintf("unknown character '%v'", string(r)))
 				}
 			}
 		}
-
-		return nil
 	}
 
 	for !isEnd() {
 		start = current
-		if err := scanToken(); err != nil {
-			return nil, err
-		}
+		startLine = line
+		startColumn = current - lineStart + 1
+		scanToken()
 	}
 
 	// cannot use addToken because lexeme will get the last character
-	tokens = append(tokens, Token{Eof, "", "", line})
+	tokens = append(tokens, Token{Eof, "", "", line, current - lineStart + 1, len(s.Text), 0, comments})
+
+	if len(errors) > 0 {
+		return tokens, errors
+	}
 
 	return tokens, nil
 }
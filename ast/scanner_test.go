@@ -29,7 +29,10 @@ func TestScanner_Scan(t *testing.T) {
 		in  string
 		out []TokenType
 	}{
-		{"(){}", []TokenType{LeftParenthesis, RightParenthesis, LeftSquare, RightSquare, Eof}},
+		{"(){}", []TokenType{LeftParenthesis, RightParenthesis, LeftBrace, RightBrace, Eof}},
+		{"[1:]", []TokenType{LeftBracket, Number, Colon, RightBracket, Eof}},
+		{"2 ** 3 % 4 * 5", []TokenType{Number, StarStar, Number, Percent, Number, Star, Number, Eof}},
+		{"fun (x) => x == 1", []TokenType{Fun, LeftParenthesis, Identifier, RightParenthesis, Arrow, Identifier, EqualEqual, Number, Eof}},
 		{"+ - * / , ; ! > <", []TokenType{Plus, Minus, Star, Slash, Comma, Semicolon, Not, Greater, Less, Eof}},
 		{"== != >= <=", []TokenType{EqualEqual, NotEqual, GreaterEqual, LessEqual, Eof}},
 		{"// This text have to be ignored", []TokenType{Eof}},
@@ -40,6 +43,9 @@ func TestScanner_Scan(t *testing.T) {
 		{"fun return", []TokenType{Fun, Return, Eof}},
 		{"class var nil", []TokenType{Class, Var, Nil, Eof}},
 		{"print x", []TokenType{Print, Identifier, Eof}},
+		{"class B < A", []TokenType{Class, Identifier, Less, Identifier, Eof}},
+		{"super.method", []TokenType{Super, Dot, Identifier, Eof}},
+		{"this.field = x", []TokenType{This, Dot, Identifier, Equal, Identifier, Eof}},
 	}
 
 	for _, test := range table {
@@ -59,3 +65,30 @@ func TestScanner_Scan(t *testing.T) {
 		})
 	}
 }
+
+func TestScanner_ScanErrors(t *testing.T) {
+	scanner := Scanner{"var a = 1 @ 2;\nprint #;\nprint \"abc"}
+
+	_, err := scanner.Scan()
+
+	errors, ok := err.(ErrorList)
+	if !ok {
+		t.Fatalf("want ErrorList, got %v", err)
+	}
+
+	want := []Error{
+		{Span{1, 11, 10, 1}, "unknown character '@'"},
+		{Span{2, 7, 21, 1}, "unknown character '#'"},
+		{Span{3, 7, 30, 4}, "unterminated string"},
+	}
+
+	if len(errors) != len(want) {
+		t.Fatalf("want %d errors, got %d: %v", len(want), len(errors), errors)
+	}
+
+	for i := range want {
+		if errors[i] != want[i] {
+			t.Errorf("want %v, got %v", want[i], errors[i])
+		}
+	}
+}
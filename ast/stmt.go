@@ -22,47 +22,113 @@
 
 package ast
 
+import (
+	"path/filepath"
+	"strings"
+)
+
 type Stmt interface {
 	Accept(StmtVisitor) error
 }
 
 type StmtVisitor interface {
 	visitBlock(Block) error
+	visitBreakStmt(BreakStmt) error
 	visitClassStmt(ClassStmt) error
+	visitContinueStmt(ContinueStmt) error
 	visitDeclaration(Declaration) error
 	visitForStmt(ForStmt) error
 	visitFunction(Function) error
 	visitIfStmt(IfStmt) error
+	visitImportStmt(ImportStmt) error
 	visitExprStmt(ExprStmt) error
 	visitPrintStmt(PrintStmt) error
 	visitReturnStmt(ReturnStmt) error
+	visitThrowStmt(ThrowStmt) error
+	visitTryStmt(TryStmt) error
 	visitWhileStmt(WhileStmt) error
 }
 
+// StmtSpan returns the span of stmt.
+func StmtSpan(stmt Stmt) Span {
+	switch s := stmt.(type) {
+	case Block:
+		return s.Span
+	case BreakStmt:
+		return s.Span
+	case ClassStmt:
+		return s.Span
+	case ContinueStmt:
+		return s.Span
+	case Declaration:
+		return s.Span
+	case ExprStmt:
+		return s.Span
+	case ForStmt:
+		return s.Span
+	case Function:
+		return s.Span
+	case IfStmt:
+		return s.Span
+	case ImportStmt:
+		return s.Span
+	case PrintStmt:
+		return s.Span
+	case ReturnStmt:
+		return s.Span
+	case ThrowStmt:
+		return s.Span
+	case TryStmt:
+		return s.Span
+	case WhileStmt:
+		return s.Span
+	}
+
+	return Span{}
+}
+
 type Block struct {
 	Stmts []Stmt
+	Span  Span
 }
 
 func (b Block) Accept(visitor StmtVisitor) error {
 	return visitor.visitBlock(b)
 }
 
+type BreakStmt struct {
+	Keyword Token
+	Span    Span
+}
+
+func (b BreakStmt) Accept(visitor StmtVisitor) error {
+	return visitor.visitBreakStmt(b)
+}
+
 type ClassStmt struct {
-	Name    Token
-	Methods []Function
+	Name       Token
+	Superclass *Variable
+	Methods    []Function
+	Span       Span
 }
 
 func (c ClassStmt) Accept(visitor StmtVisitor) error {
 	return visitor.visitClassStmt(c)
 }
 
-func (c ClassStmt) CreateInstance() Literal {
-	return Literal{ClassInstance{c, make(map[string]Literal)}}
+type ContinueStmt struct {
+	Keyword Token
+	Span    Span
+}
+
+func (c ContinueStmt) Accept(visitor StmtVisitor) error {
+	return visitor.visitContinueStmt(c)
 }
 
 type Declaration struct {
 	Token
 	Expr
+	Span Span
 }
 
 func (d Declaration) Accept(visitor StmtVisitor) error {
@@ -74,6 +140,7 @@ type ForStmt struct {
 	Condition Expr
 	Increment Expr
 	Body      Stmt
+	Span      Span
 }
 
 func (f ForStmt) Accept(visitor StmtVisitor) error {
@@ -84,14 +151,39 @@ type IfStmt struct {
 	Condition Expr
 	Then      Stmt
 	Else      Stmt
+	Span      Span
 }
 
 func (i IfStmt) Accept(visitor StmtVisitor) error {
 	return visitor.visitIfStmt(i)
 }
 
+// ImportStmt loads the module at Path, binding it to Name or, when Name is
+// nil, to the base name of the file.
+type ImportStmt struct {
+	Keyword Token
+	Name    *Token
+	Path    Token
+	Span    Span
+}
+
+func (i ImportStmt) Accept(visitor StmtVisitor) error {
+	return visitor.visitImportStmt(i)
+}
+
+// name returns the variable the module is bound to.
+func (i ImportStmt) name() string {
+	if i.Name != nil {
+		return i.Name.Lexeme
+	}
+
+	base := filepath.Base(i.Path.Literal)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
 type ExprStmt struct {
 	Expr
+	Span Span
 }
 
 func (e ExprStmt) Accept(visitor StmtVisitor) error {
@@ -99,10 +191,14 @@ func (e ExprStmt) Accept(visitor StmtVisitor) error {
 }
 
 type Function struct {
-	Name      Token
-	Closure   *Environment
-	Arguments []Token
-	Body      []Stmt
+	Name        Token
+	Closure     *Environment
+	Locals      map[int]int
+	File        string
+	Arguments   []Token
+	Body        []Stmt
+	Initializer bool
+	Span        Span
 }
 
 func (f Function) Accept(visitor StmtVisitor) error {
@@ -111,6 +207,7 @@ func (f Function) Accept(visitor StmtVisitor) error {
 
 type PrintStmt struct {
 	Expr
+	Span Span
 }
 
 func (p PrintStmt) Accept(visitor StmtVisitor) error {
@@ -118,16 +215,43 @@ func (p PrintStmt) Accept(visitor StmtVisitor) error {
 }
 
 type ReturnStmt struct {
+	Keyword Token
 	Expr
+	Span Span
 }
 
 func (r ReturnStmt) Accept(visitor StmtVisitor) error {
 	return visitor.visitReturnStmt(r)
 }
 
+type ThrowStmt struct {
+	Keyword Token
+	Expr
+	Span Span
+}
+
+func (t ThrowStmt) Accept(visitor StmtVisitor) error {
+	return visitor.visitThrowStmt(t)
+}
+
+// TryStmt runs Body and, if it fails, Catch with the error bound to Name.
+// Finally runs in any case. Either Catch or Finally can be nil.
+type TryStmt struct {
+	Body    Block
+	Name    Token
+	Catch   *Block
+	Finally *Block
+	Span    Span
+}
+
+func (t TryStmt) Accept(visitor StmtVisitor) error {
+	return visitor.visitTryStmt(t)
+}
+
 type WhileStmt struct {
 	Condition Expr
 	Body      Stmt
+	Span      Span
 }
 
 func (w WhileStmt) Accept(visitor StmtVisitor) error {
@@ -0,0 +1,197 @@
+//  MIT License
+//
+//  Copyright (c) 2019 Marco Pacini
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy
+//  of this software and associated documentation files (the "Software"), to deal
+//  in the Software without restriction, including without limitation the rights
+//  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//  copies of the Software, and to permit persons to whom the Software is
+//  furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all
+//  copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//  SOFTWARE.
+
+package ast
+
+import (
+	"fmt"
+	"strings"
+)
+
+// stringArgument returns the j-th argument of the native name, which must be
+// a string.
+func stringArgument(name string, arguments []Literal, j int) (string, error) {
+	s, ok := arguments[j].Value.(string)
+	if !ok {
+		return "", fmt.Errorf("%s: argument %d: expected string, got %v", name, j+1, arguments[j])
+	}
+
+	return s, nil
+}
+
+// stringFunction returns a native taking and returning a single string.
+func stringFunction(name string, fn func(string) string) Native {
+	return NewNative(name, 1, func(i *Interpreter, arguments []Literal) (Literal, error) {
+		s, err := stringArgument(name, arguments, 0)
+		if err != nil {
+			return Literal{}, err
+		}
+
+		return Literal{Value: fn(s)}, nil
+	})
+}
+
+// stringPredicate returns a native testing a string against another one.
+func stringPredicate(name string, fn func(string, string) bool) Native {
+	return NewNative(name, 2, func(i *Interpreter, arguments []Literal) (Literal, error) {
+		s, err := stringArgument(name, arguments, 0)
+		if err != nil {
+			return Literal{}, err
+		}
+
+		t, err := stringArgument(name, arguments, 1)
+		if err != nil {
+			return Literal{}, err
+		}
+
+		return Literal{Value: fn(s, t)}, nil
+	})
+}
+
+var stringNatives = []Native{
+	stringFunction("upper", strings.ToUpper),
+	stringFunction("lower", strings.ToLower),
+	stringFunction("trim", strings.TrimSpace),
+	stringPredicate("startsWith", strings.HasPrefix),
+	stringPredicate("endsWith", strings.HasSuffix),
+	stringPredicate("contains", strings.Contains),
+	NewNative("substr", -1, func(i *Interpreter, arguments []Literal) (Literal, error) {
+		if len(arguments) != 2 && len(arguments) != 3 {
+			return Literal{}, fmt.Errorf("substr: expected 2 or 3 arguments but got %d", len(arguments))
+		}
+
+		s, err := stringArgument("substr", arguments, 0)
+		if err != nil {
+			return Literal{}, err
+		}
+
+		var end Literal
+		if len(arguments) == 3 {
+			end = arguments[2]
+		}
+
+		runes := []rune(s)
+
+		from, to, err := bounds(arguments[1], end, len(runes))
+		if err != nil {
+			return Literal{}, fmt.Errorf("substr: %v", err)
+		}
+
+		return Literal{Value: string(runes[from:to])}, nil
+	}),
+	NewNative("index", 2, func(i *Interpreter, arguments []Literal) (Literal, error) {
+		s, err := stringArgument("index", arguments, 0)
+		if err != nil {
+			return Literal{}, err
+		}
+
+		sub, err := stringArgument("index", arguments, 1)
+		if err != nil {
+			return Literal{}, err
+		}
+
+		n := strings.Index(s, sub)
+		if n >= 0 {
+			// position in characters, not bytes
+			n = len([]rune(s[:n]))
+		}
+
+		return Literal{Value: float64(n)}, nil
+	}),
+	NewNative("split", 2, func(i *Interpreter, arguments []Literal) (Literal, error) {
+		s, err := stringArgument("split", arguments, 0)
+		if err != nil {
+			return Literal{}, err
+		}
+
+		sep, err := stringArgument("split", arguments, 1)
+		if err != nil {
+			return Literal{}, err
+		}
+
+		parts := strings.Split(s, sep)
+
+		elements := make([]Literal, len(parts))
+		for j, part := range parts {
+			elements[j] = Literal{Value: part}
+		}
+
+		return Literal{Value: NewList(elements)}, nil
+	}),
+	NewNative("join", 2, func(i *Interpreter, arguments []Literal) (Literal, error) {
+		l, ok := arguments[0].Value.(*List)
+		if !ok {
+			return Literal{}, fmt.Errorf("join: argument 1: expected list, got %v", arguments[0])
+		}
+
+		sep, err := stringArgument("join", arguments, 1)
+		if err != nil {
+			return Literal{}, err
+		}
+
+		parts := make([]string, len(l.Elements))
+		for j, e := range l.Elements {
+			parts[j] = e.String()
+		}
+
+		return Literal{Value: strings.Join(parts, sep)}, nil
+	}),
+	NewNative("replace", 3, func(i *Interpreter, arguments []Literal) (Literal, error) {
+		var s [3]string
+
+		for j := range s {
+			var err error
+			if s[j], err = stringArgument("replace", arguments, j); err != nil {
+				return Literal{}, err
+			}
+		}
+
+		return Literal{Value: strings.ReplaceAll(s[0], s[1], s[2])}, nil
+	}),
+	// format replaces each {} in its first argument with the next argument
+	NewNative("format", -1, func(i *Interpreter, arguments []Literal) (Literal, error) {
+		if len(arguments) == 0 {
+			return Literal{}, fmt.Errorf("format: expected at least 1 argument but got 0")
+		}
+
+		f, err := stringArgument("format", arguments, 0)
+		if err != nil {
+			return Literal{}, err
+		}
+
+		parts := strings.Split(f, "{}")
+		if len(parts)-1 != len(arguments)-1 {
+			return Literal{}, fmt.Errorf("format: %d placeholders but %d values", len(parts)-1, len(arguments)-1)
+		}
+
+		var b strings.Builder
+		for j, part := range parts {
+			b.WriteString(part)
+
+			if j+1 < len(arguments) {
+				b.WriteString(arguments[j+1].String())
+			}
+		}
+
+		return Literal{Value: b.String()}, nil
+	}),
+}
@@ -0,0 +1,111 @@
+//  MIT License
+//
+//  Copyright (c) 2019 Marco Pacini
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy
+//  of this software and associated documentation files (the "Software"), to deal
+//  in the Software without restriction, including without limitation the rights
+//  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//  copies of the Software, and to permit persons to whom the Software is
+//  furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all
+//  copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//  SOFTWARE.
+
+package ast
+
+import "testing"
+
+func TestStringNatives(t *testing.T) {
+	table := []struct {
+		in  string
+		out interface{}
+		err string
+	}{
+		{"upper(\"héllo\")", "HÉLLO", ""},
+		{"upper(\"\")", "", ""},
+		{"upper(1)", nil, "upper: argument 1: expected string, got 1"},
+		{"lower(\"ÀBC\")", "àbc", ""},
+		{"lower(nil)", nil, "lower: argument 1: expected string, got nil"},
+		{"trim(\"  a b \t\")", "a b", ""},
+		{"trim(\"\")", "", ""},
+		{"trim([])", nil, "trim: argument 1: expected string, got []"},
+		{"startsWith(\"héllo\", \"hé\")", true, ""},
+		{"startsWith(\"\", \"\")", true, ""},
+		{"startsWith(\"a\", 1)", nil, "startsWith: argument 2: expected string, got 1"},
+		{"endsWith(\"héllo\", \"lo\")", true, ""},
+		{"endsWith(\"lo\", \"hello\")", false, ""},
+		{"contains(\"日本語\", \"本\")", true, ""},
+		{"contains(\"abc\", \"\")", true, ""},
+		{"contains(true, \"a\")", nil, "contains: argument 1: expected string, got true"},
+		{"substr(\"héllo\", 1)", "éllo", ""},
+		{"substr(\"héllo\", -3, -1)", "ll", ""},
+		{"substr(\"\", 0, 0)", "", ""},
+		{"substr(\"abc\", 1, 9)", "bc", ""},
+		{"substr(\"abc\", 5)", "", ""},
+		{"substr(\"abc\", 1.5)", nil, "substr: slice bounds must be integers, got 1.5"},
+		{"substr(\"abc\")", nil, "substr: expected 2 or 3 arguments but got 1"},
+		{"substr(1, 0)", nil, "substr: argument 1: expected string, got 1"},
+		{"index(\"日本語\", \"語\")", 2.0, ""},
+		{"index(\"abc\", \"x\")", -1.0, ""},
+		{"index(\"\", \"\")", 0.0, ""},
+		{"index(\"abc\", nil)", nil, "index: argument 2: expected string, got nil"},
+		{"join(split(\"a,b,,c\", \",\"), \"|\")", "a|b||c", ""},
+		{"len(split(\"\", \",\"))", 1.0, ""},
+		{"join(split(\"héllo\", \"\"), \"-\")", "h-é-l-l-o", ""},
+		{"split(1, \",\")", nil, "split: argument 1: expected string, got 1"},
+		{"join([1, \"é\", nil], \"\")", "1énil", ""},
+		{"join([], \",\")", "", ""},
+		{"join(\"abc\", \",\")", nil, "join: argument 1: expected list, got abc"},
+		{"join([], 1)", nil, "join: argument 2: expected string, got 1"},
+		{"replace(\"héhé\", \"é\", \"e\")", "hehe", ""},
+		{"replace(\"\", \"a\", \"b\")", "", ""},
+		{"replace(\"a\", \"a\", 1)", nil, "replace: argument 3: expected string, got 1"},
+		{"format(\"{} + {} = {}\", 1, 2, \"três\")", "1 + 2 = três", ""},
+		{"format(\"\")", "", ""},
+		{"format(\"{}\")", nil, "format: 1 placeholders but 0 values"},
+		{"format()", nil, "format: expected at least 1 argument but got 0"},
+		{"format(1)", nil, "format: argument 1: expected string, got 1"},
+		{"len(\"héllo\")", 5.0, ""},
+		{"\"héllo\"[1]", "é", ""},
+		{"\"héllo\"[-1]", "o", ""},
+		{"\"héllo\"[1:3]", "él", ""},
+		{"\"\"[0:0]", "", ""},
+		{"\"abc\"[3]", nil, "index 3 out of range for length 3"},
+		{"\"\"[0]", nil, "index 0 out of range for length 0"},
+		{"\"abc\"[\"a\"]", nil, "index must be an integer, got a"},
+		{"\"abc\"[-4]", nil, "index -4 out of range for length 3"},
+	}
+
+	i := NewInterpreter()
+
+	for _, test := range table {
+		t.Run(test.in, func(t *testing.T) {
+			out, err := evaluate(i, test.in)
+
+			if test.err != "" {
+				if message(err) != test.err {
+					t.Fatalf("want error %q, got %v", test.err, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if out != test.out {
+				t.Errorf("want %q, got %q", test.out, out)
+			}
+		})
+	}
+}
@@ -0,0 +1,228 @@
+//  MIT License
+//
+//  Copyright (c) 2019 Marco Pacini
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy
+//  of this software and associated documentation files (the "Software"), to deal
+//  in the Software without restriction, including without limitation the rights
+//  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//  copies of the Software, and to permit persons to whom the Software is
+//  furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all
+//  copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//  SOFTWARE.
+
+package ast
+
+import (
+	"fmt"
+	"strings"
+)
+
+type SymbolKind int
+
+const (
+	VariableSymbol SymbolKind = iota
+	ParameterSymbol
+	FunctionSymbol
+	ClassSymbol
+	MethodSymbol
+	ModuleSymbol
+)
+
+func (k SymbolKind) String() string {
+	switch k {
+	case VariableSymbol:
+		return "variable"
+	case ParameterSymbol:
+		return "parameter"
+	case FunctionSymbol:
+		return "function"
+	case ClassSymbol:
+		return "class"
+	case MethodSymbol:
+		return "method"
+	case ModuleSymbol:
+		return "module"
+	}
+
+	return "unknown"
+}
+
+// Symbol is a name declared by a program.
+type Symbol struct {
+	Name string
+	Kind SymbolKind
+	// Parameters of a function or method, or of the initializer of a class
+	Parameters []string
+	// Declaration is the span of the name, Span the one of the declaration.
+	Declaration Span
+	Span        Span
+	References  []Span
+	// Methods of a class
+	Methods []*Symbol
+}
+
+// Signature describes the symbol as declared, as in "function f(a, b)".
+func (s *Symbol) Signature() string {
+	switch s.Kind {
+	case FunctionSymbol, ClassSymbol, MethodSymbol:
+		return fmt.Sprintf("%v %s(%s)", s.Kind, s.Name, strings.Join(s.Parameters, ", "))
+	}
+
+	return fmt.Sprintf("%v %s", s.Kind, s.Name)
+}
+
+// SymbolScope is a scope of a program with the symbols declared in it. The
+// global scope has no parent.
+type SymbolScope struct {
+	Span     Span
+	Parent   *SymbolScope
+	Children []*SymbolScope
+	Symbols  []*Symbol
+	names    map[string]*Symbol
+}
+
+func newSymbolScope(span Span, parent *SymbolScope) *SymbolScope {
+	s := &SymbolScope{span, parent, nil, nil, make(map[string]*Symbol)}
+
+	if parent != nil {
+		parent.Children = append(parent.Children, s)
+	}
+
+	return s
+}
+
+// Symbols collects the declarations of a program and the names referring to
+// them, when set on the Resolver that resolves the program.
+type Symbols struct {
+	Global *SymbolScope
+	// Names maps the offset of each name, declared or referenced, to its
+	// symbol.
+	Names map[int]*Symbol
+
+	// the scopes open while resolving, in the order of the resolver stack
+	stack []*SymbolScope
+	// references to names not declared yet when resolved
+	unresolved []Token
+}
+
+func NewSymbols() *Symbols {
+	return &Symbols{Global: newSymbolScope(Span{}, nil), Names: make(map[int]*Symbol)}
+}
+
+// At returns the innermost scope containing offset.
+func (s *Symbols) At(offset int) *SymbolScope {
+	scope := s.Global
+
+	for {
+		var inner *SymbolScope
+		for _, child := range scope.Children {
+			if child.Span.Offset <= offset && offset < child.Span.Offset+child.Span.Length {
+				inner = child
+			}
+		}
+
+		if inner == nil {
+			return scope
+		}
+
+		scope = inner
+	}
+}
+
+// Visible returns the symbols that can be referred to at offset, the inner
+// ones first. Locals are visible after their declaration, globals anywhere.
+func (s *Symbols) Visible(offset int) []*Symbol {
+	var visible []*Symbol
+	seen := make(map[string]bool)
+
+	for scope := s.At(offset); scope != nil; scope = scope.Parent {
+		for j := len(scope.Symbols) - 1; j >= 0; j-- {
+			sym := scope.Symbols[j]
+
+			if seen[sym.Name] || (scope.Parent != nil && sym.Declaration.Offset > offset) {
+				continue
+			}
+
+			seen[sym.Name] = true
+			visible = append(visible, sym)
+		}
+	}
+
+	return visible
+}
+
+func (s *Symbols) begin(span Span) {
+	parent := s.Global
+	if len(s.stack) > 0 {
+		parent = s.stack[len(s.stack)-1]
+	}
+
+	s.stack = append(s.stack, newSymbolScope(span, parent))
+}
+
+func (s *Symbols) end() {
+	s.stack = s.stack[:len(s.stack)-1]
+}
+
+// declare adds the symbol declared by name to the innermost scope.
+func (s *Symbols) declare(name Token, kind SymbolKind, span Span) *Symbol {
+	sym := &Symbol{Name: name.Lexeme, Kind: kind, Declaration: name.Span(), Span: span}
+
+	scope := s.stack[len(s.stack)-1]
+	scope.Symbols = append(scope.Symbols, sym)
+	scope.names[name.Lexeme] = sym
+
+	s.Names[name.Offset] = sym
+
+	return sym
+}
+
+// method returns the symbol of the method m, which is not declared in any
+// scope.
+func (s *Symbols) method(m Function) *Symbol {
+	sym := &Symbol{Name: m.Name.Lexeme, Kind: MethodSymbol, Parameters: parameters(m), Declaration: m.Name.Span(), Span: m.Span}
+	s.Names[m.Name.Offset] = sym
+
+	return sym
+}
+
+func parameters(f Function) []string {
+	names := make([]string, len(f.Arguments))
+	for j, argument := range f.Arguments {
+		names[j] = argument.Lexeme
+	}
+
+	return names
+}
+
+// refer records that name refers to the symbol declared in the scope at
+// index depth of the stack.
+func (s *Symbols) refer(name Token, depth int) {
+	if sym, ok := s.stack[depth].names[name.Lexeme]; ok {
+		sym.References = append(sym.References, name.Span())
+		s.Names[name.Offset] = sym
+	}
+}
+
+// resolve binds the references to globals declared after them, as those in
+// a function body to the functions declared after it.
+func (s *Symbols) resolve() {
+	for _, name := range s.unresolved {
+		if sym, ok := s.Global.names[name.Lexeme]; ok {
+			sym.References = append(sym.References, name.Span())
+			s.Names[name.Offset] = sym
+		}
+	}
+
+	s.unresolved = nil
+}
@@ -22,28 +22,39 @@
 
 package ast
 
-import "fmt"
+import (
+	"fmt"
+	"sort"
+)
 
 type TokenType int
 
 const (
 	And TokenType = iota
+	Arrow
+	Break
+	Catch
 	Class
+	Colon
 	Comma
+	Continue
 	Dot
 	Else
 	Eof
 	Equal
 	EqualEqual
 	False
+	Finally
 	For
 	Fun
 	Greater
 	GreaterEqual
 	Identifier
 	If
+	Import
 	LeftParenthesis
-	LeftSquare
+	LeftBrace
+	LeftBracket
 	Less
 	LessEqual
 	Minus
@@ -52,39 +63,63 @@ const (
 	NotEqual
 	Number
 	Or
+	Percent
 	Plus
 	Print
 	Return
 	RightParenthesis
-	RightSquare
+	RightBrace
+	RightBracket
 	Semicolon
 	Slash
 	Star
+	StarStar
 	String
 	Super
 	This
+	Throw
 	True
+	Try
 	Var
 	While
 )
 
 var keywords = map[string]TokenType{
-	"and":    And,
-	"class":  Class,
-	"else":   Else,
-	"false":  False,
-	"fun":    Fun,
-	"for":    For,
-	"if":     If,
-	"nil":    Nil,
-	"or":     Or,
-	"print":  Print,
-	"return": Return,
-	"super":  Super,
-	"this":   This,
-	"true":   True,
-	"var":    Var,
-	"while":  While,
+	"and":      And,
+	"break":    Break,
+	"catch":    Catch,
+	"class":    Class,
+	"continue": Continue,
+	"else":     Else,
+	"false":    False,
+	"finally":  Finally,
+	"fun":      Fun,
+	"for":      For,
+	"if":       If,
+	"import":   Import,
+	"nil":      Nil,
+	"or":       Or,
+	"print":    Print,
+	"return":   Return,
+	"super":    Super,
+	"this":     This,
+	"throw":    Throw,
+	"true":     True,
+	"try":      Try,
+	"var":      Var,
+	"while":    While,
+}
+
+// Keywords returns the reserved words, sorted.
+func Keywords() []string {
+	words := make([]string, 0, len(keywords))
+	for word := range keywords {
+		words = append(words, word)
+	}
+
+	sort.Strings(words)
+
+	return words
 }
 
 func (t TokenType) String() string {
@@ -93,10 +128,18 @@ func (t TokenType) String() string {
 		return "LEFT_PARENTHESIS"
 	case RightParenthesis:
 		return "RIGHT_PARENTHESIS"
-	case LeftSquare:
-		return "LEFT_SQUARE"
-	case RightSquare:
-		return "RIGHT_SQUARE"
+	case LeftBrace:
+		return "LEFT_BRACE"
+	case RightBrace:
+		return "RIGHT_BRACE"
+	case LeftBracket:
+		return "LEFT_BRACKET"
+	case RightBracket:
+		return "RIGHT_BRACKET"
+	case Arrow:
+		return "ARROW"
+	case Colon:
+		return "COLON"
 	case Comma:
 		return "COMMA"
 	case Dot:
@@ -111,6 +154,10 @@ func (t TokenType) String() string {
 		return "SLASH"
 	case Star:
 		return "STAR"
+	case StarStar:
+		return "STAR_STAR"
+	case Percent:
+		return "PERCENT"
 	case Not:
 		return "NOT"
 	case Equal:
@@ -157,6 +204,24 @@ func (t TokenType) String() string {
 		return "FALSE"
 	case Nil:
 		return "NIL"
+	case Import:
+		return "IMPORT"
+	case Break:
+		return "BREAK"
+	case Continue:
+		return "CONTINUE"
+	case Try:
+		return "TRY"
+	case Catch:
+		return "CATCH"
+	case Finally:
+		return "FINALLY"
+	case Throw:
+		return "THROW"
+	case Super:
+		return "SUPER"
+	case This:
+		return "THIS"
 	}
 
 	return "UNKNOWN"
@@ -167,8 +232,24 @@ type Token struct {
 	Lexeme  string
 	Literal string
 	Line    int
+	Column  int
+	Offset  int
+	Length  int
+	// Comments are the comments between the previous token and this one.
+	Comments []Comment
+}
+
+// Comment is a line comment, kept as trivia of the token that follows it.
+type Comment struct {
+	Text string
+	Span
+}
+
+// Span returns the source range covered by the token.
+func (t Token) Span() Span {
+	return Span{t.Line, t.Column, t.Offset, t.Length}
 }
 
 func (t Token) String() string {
-	return fmt.Sprintf("%v %v %v %d", t.TokenType, t.Lexeme, t.Literal, t.Line)
+	return fmt.Sprintf("%v %v %v %d:%d", t.TokenType, t.Lexeme, t.Literal, t.Line, t.Column)
 }
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"github.com/marcopacini/go-lox/ast"
+	"github.com/marcopacini/go-lox/debug"
+	"io/ioutil"
+	"os"
+)
+
+// runDebug runs the script named in args, pausing at its first statement to
+// read debugger commands from standard input, and returns the exit status.
+func runDebug(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: lox debug script")
+		return 64
+	}
+
+	b, err := ioutil.ReadFile(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitNoInput
+	}
+
+	i := ast.NewInterpreter()
+	setPath(i, args[0])
+
+	// the debugger talks on standard error, leaving the output of the program
+	// on standard output
+	i.Debugger = debug.NewConsole(i.Path, string(b), os.Stdin, os.Stderr)
+
+	fmt.Fprintln(os.Stderr, "type help for a list of commands")
+
+	if err := run(i, string(b), false); err != nil && err != debug.ErrQuit {
+		report(string(b), err)
+		return exitStatus(err)
+	}
+
+	return 0
+}
@@ -0,0 +1,505 @@
+//  MIT License
+//
+//  Copyright (c) 2019 Marco Pacini
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy
+//  of this software and associated documentation files (the "Software"), to deal
+//  in the Software without restriction, including without limitation the rights
+//  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//  copies of the Software, and to permit persons to whom the Software is
+//  furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all
+//  copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//  SOFTWARE.
+
+package debug
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/marcopacini/go-lox/ast"
+)
+
+// message is a request, a response or an event of the Debug Adapter Protocol.
+type message struct {
+	Seq     int    `json:"seq"`
+	Type    string `json:"type"`
+	Command string `json:"command,omitempty"`
+	Event   string `json:"event,omitempty"`
+
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+
+	RequestSeq int         `json:"request_seq,omitempty"`
+	Success    *bool       `json:"success,omitempty"`
+	Message    string      `json:"message,omitempty"`
+	Body       interface{} `json:"body,omitempty"`
+}
+
+// the only thread of a Lox program
+const threadID = 1
+
+// Variable references of the scopes of the innermost frame.
+const (
+	localsReference = iota + 1
+	globalsReference
+)
+
+// Adapter is a Debugger serving the Debug Adapter Protocol over a pair of
+// streams, for editors to run a program and pause it. Requests about a
+// paused program are run by the goroutine of the program, which waits for
+// them in Before.
+type Adapter struct {
+	in  *bufio.Reader
+	out io.Writer
+
+	// mu guards the writes to out and the fields below
+	mu      sync.Mutex
+	seq     int
+	paused  bool
+	quit    bool
+	stepper *Stepper
+
+	program  string
+	entry    bool
+	commands chan func(i *ast.Interpreter, stmt ast.Stmt) bool
+	done     chan struct{}
+}
+
+func NewAdapter(in io.Reader, out io.Writer) *Adapter {
+	return &Adapter{
+		in:       bufio.NewReader(in),
+		out:      out,
+		stepper:  NewStepper("", false),
+		commands: make(chan func(*ast.Interpreter, ast.Stmt) bool),
+	}
+}
+
+// Run serves requests until the editor disconnects or closes the input.
+func (a *Adapter) Run() error {
+	for {
+		header, err := textproto.NewReader(a.in).ReadMIMEHeader()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+
+			return err
+		}
+
+		length, err := strconv.Atoi(header.Get("Content-Length"))
+		if err != nil {
+			return fmt.Errorf("invalid content length %q", header.Get("Content-Length"))
+		}
+
+		content := make([]byte, length)
+		if _, err := io.ReadFull(a.in, content); err != nil {
+			return err
+		}
+
+		var request message
+		if err := json.Unmarshal(content, &request); err != nil {
+			return err
+		}
+
+		body, err := a.handle(request)
+
+		response := message{Type: "response", Command: request.Command, RequestSeq: request.Seq, Body: body}
+		success := err == nil
+		if !success {
+			response.Message, response.Body = err.Error(), nil
+		}
+		response.Success = &success
+
+		a.send(response)
+
+		switch request.Command {
+		case "initialize":
+			a.event("initialized", nil)
+		case "disconnect":
+			a.stop()
+			return nil
+		}
+	}
+}
+
+func (a *Adapter) send(m message) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.seq++
+	m.Seq = a.seq
+
+	content, err := json.Marshal(m)
+	if err != nil {
+		return
+	}
+
+	fmt.Fprintf(a.out, "Content-Length: %d\r\n\r\n%s", len(content), content)
+}
+
+func (a *Adapter) event(name string, body interface{}) {
+	a.send(message{Type: "event", Event: name, Body: body})
+}
+
+func (a *Adapter) handle(request message) (interface{}, error) {
+	switch request.Command {
+	case "initialize":
+		return map[string]bool{
+			"supportsConfigurationDoneRequest": true,
+			"supportsEvaluateForHovers":        true,
+		}, nil
+
+	case "launch":
+		var arguments struct {
+			Program     string `json:"program"`
+			StopOnEntry bool   `json:"stopOnEntry"`
+		}
+		if err := json.Unmarshal(request.Arguments, &arguments); err != nil {
+			return nil, err
+		}
+
+		a.program, a.entry = arguments.Program, arguments.StopOnEntry
+		a.stepper.Path = arguments.Program
+
+		return nil, nil
+
+	case "setBreakpoints":
+		var arguments struct {
+			Breakpoints []struct {
+				Line int `json:"line"`
+			} `json:"breakpoints"`
+		}
+		if err := json.Unmarshal(request.Arguments, &arguments); err != nil {
+			return nil, err
+		}
+
+		var lines []int
+		var breakpoints []map[string]interface{}
+		for _, b := range arguments.Breakpoints {
+			lines = append(lines, b.Line)
+			breakpoints = append(breakpoints, map[string]interface{}{"verified": true, "line": b.Line})
+		}
+
+		a.stepper.SetBreakpoints(lines)
+
+		return map[string]interface{}{"breakpoints": breakpoints}, nil
+
+	case "configurationDone":
+		return nil, a.start()
+
+	case "threads":
+		return map[string]interface{}{
+			"threads": []map[string]interface{}{{"id": threadID, "name": "main"}},
+		}, nil
+
+	case "stackTrace":
+		var stack []map[string]interface{}
+		err := a.inspect(func(i *ast.Interpreter, stmt ast.Stmt) error {
+			for j, f := range frames(i, stmt) {
+				stack = append(stack, map[string]interface{}{
+					"id":     j,
+					"name":   f.name,
+					"line":   f.line,
+					"column": 1,
+					"source": map[string]string{"path": a.program},
+				})
+			}
+
+			return nil
+		})
+
+		return map[string]interface{}{"stackFrames": stack, "totalFrames": len(stack)}, err
+
+	case "scopes":
+		var arguments struct {
+			FrameID int `json:"frameId"`
+		}
+		if err := json.Unmarshal(request.Arguments, &arguments); err != nil {
+			return nil, err
+		}
+
+		// only the environment of the innermost frame is known
+		scopes := []map[string]interface{}{}
+		if arguments.FrameID == 0 {
+			scopes = append(scopes,
+				map[string]interface{}{"name": "Locals", "variablesReference": localsReference, "expensive": false},
+				map[string]interface{}{"name": "Globals", "variablesReference": globalsReference, "expensive": false})
+		}
+
+		return map[string]interface{}{"scopes": scopes}, nil
+
+	case "variables":
+		var arguments struct {
+			VariablesReference int `json:"variablesReference"`
+		}
+		if err := json.Unmarshal(request.Arguments, &arguments); err != nil {
+			return nil, err
+		}
+
+		variables := []map[string]interface{}{}
+		err := a.inspect(func(i *ast.Interpreter, stmt ast.Stmt) error {
+			scopes := i.Scopes()
+
+			// an inner variable hides the outer ones with the same name
+			scope := make(map[string]ast.Literal)
+			if arguments.VariablesReference == globalsReference {
+				scope = scopes[len(scopes)-1]
+			} else {
+				for j := len(scopes) - 2; j >= 0; j-- {
+					for name, value := range scopes[j] {
+						scope[name] = value
+					}
+				}
+			}
+
+			for _, name := range names(scope) {
+				variables = append(variables, map[string]interface{}{
+					"name":               name,
+					"value":              repr(scope[name]),
+					"variablesReference": 0,
+				})
+			}
+
+			return nil
+		})
+
+		return map[string]interface{}{"variables": variables}, err
+
+	case "evaluate":
+		var arguments struct {
+			Expression string `json:"expression"`
+		}
+		if err := json.Unmarshal(request.Arguments, &arguments); err != nil {
+			return nil, err
+		}
+
+		var result string
+		err := a.inspect(func(i *ast.Interpreter, stmt ast.Stmt) error {
+			l, err := i.Eval(arguments.Expression)
+			result = repr(l)
+			return err
+		})
+
+		return map[string]interface{}{"result": result, "variablesReference": 0}, err
+
+	case "continue":
+		return map[string]bool{"allThreadsContinued": true}, a.resume(func(i *ast.Interpreter) {
+			a.stepper.Continue()
+		})
+
+	case "next":
+		return nil, a.resume(a.stepper.StepOver)
+
+	case "stepIn":
+		return nil, a.resume(func(i *ast.Interpreter) {
+			a.stepper.StepIn()
+		})
+
+	case "stepOut":
+		return nil, a.resume(a.stepper.StepOut)
+
+	case "pause":
+		a.stepper.StepIn()
+		return nil, nil
+
+	case "disconnect":
+		return nil, nil
+	}
+
+	return nil, fmt.Errorf("unsupported request %q", request.Command)
+}
+
+// start runs the launched program, telling the editor about its output and
+// its end.
+func (a *Adapter) start() error {
+	source, err := ioutil.ReadFile(a.program)
+	if err != nil {
+		return err
+	}
+
+	scanner := ast.Scanner{Text: string(source)}
+	tokens, err := scanner.Scan()
+	if err != nil {
+		return err
+	}
+
+	parser := ast.Parser{Tokens: tokens}
+	stmts, err := parser.Parse()
+	if err != nil {
+		return err
+	}
+
+	if a.entry {
+		a.stepper.StepIn()
+	}
+
+	i := ast.NewInterpreter()
+	i.Path = a.program
+	i.Stdout = output{a, "stdout"}
+	i.Stderr = output{a, "stderr"}
+	// the standard input carries the protocol
+	i.Stdin = strings.NewReader("")
+	i.Debugger = a
+
+	a.done = make(chan struct{})
+
+	go func() {
+		defer close(a.done)
+
+		code := 0
+		switch err := i.Run(stmts).(type) {
+		case nil:
+		case ast.RuntimeError:
+			code = 70
+
+			text := source
+			if err.File != "" {
+				text, _ = ioutil.ReadFile(err.File)
+			}
+
+			fmt.Fprintln(i.Stderr, err.Render(string(text)))
+		case ast.ModuleError:
+			code = 65
+
+			text, _ := ioutil.ReadFile(err.File)
+			fmt.Fprintln(i.Stderr, err.Render(string(text)))
+		default:
+			if err != ErrQuit {
+				code = 65
+				fmt.Fprintln(i.Stderr, err)
+			}
+		}
+
+		a.event("exited", map[string]int{"exitCode": code})
+		a.event("terminated", nil)
+	}()
+
+	return nil
+}
+
+func (a *Adapter) Before(i *ast.Interpreter, stmt ast.Stmt) error {
+	a.mu.Lock()
+	quit := a.quit
+	a.mu.Unlock()
+
+	if quit {
+		return ErrQuit
+	}
+
+	reason, ok := a.stepper.Stop(i, stmt)
+	if !ok {
+		return nil
+	}
+
+	// checked again, since stop may have been called while deciding to pause
+	a.mu.Lock()
+	if a.quit {
+		a.mu.Unlock()
+		return ErrQuit
+	}
+	a.paused = true
+	a.mu.Unlock()
+
+	a.event("stopped", map[string]interface{}{"reason": reason, "threadId": threadID, "allThreadsStopped": true})
+
+	for command := range a.commands {
+		if command(i, stmt) {
+			break
+		}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.quit {
+		return ErrQuit
+	}
+
+	return nil
+}
+
+// inspect runs f on the paused program.
+func (a *Adapter) inspect(f func(i *ast.Interpreter, stmt ast.Stmt) error) error {
+	a.mu.Lock()
+	paused := a.paused
+	a.mu.Unlock()
+
+	if !paused {
+		return fmt.Errorf("the program is not paused")
+	}
+
+	errs := make(chan error)
+	a.commands <- func(i *ast.Interpreter, stmt ast.Stmt) bool {
+		errs <- f(i, stmt)
+		return false
+	}
+
+	return <-errs
+}
+
+// resume lets the paused program run, after f has set where it stops next.
+func (a *Adapter) resume(f func(i *ast.Interpreter)) error {
+	a.mu.Lock()
+	paused := a.paused
+	a.paused = false
+	a.mu.Unlock()
+
+	if !paused {
+		return fmt.Errorf("the program is not paused")
+	}
+
+	a.commands <- func(i *ast.Interpreter, stmt ast.Stmt) bool {
+		f(i)
+		return true
+	}
+
+	return nil
+}
+
+// stop ends the program, if it is running, and waits for it.
+func (a *Adapter) stop() {
+	if a.done == nil {
+		return
+	}
+
+	a.mu.Lock()
+	a.quit = true
+	paused := a.paused
+	a.paused = false
+	a.mu.Unlock()
+
+	if paused {
+		a.commands <- func(*ast.Interpreter, ast.Stmt) bool {
+			return true
+		}
+	}
+
+	<-a.done
+}
+
+// output sends what the program writes to a stream as output events.
+type output struct {
+	adapter  *Adapter
+	category string
+}
+
+func (o output) Write(p []byte) (int, error) {
+	o.adapter.event("output", map[string]string{"category": o.category, "output": string(p)})
+	return len(p), nil
+}
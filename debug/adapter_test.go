@@ -0,0 +1,158 @@
+//  MIT License
+//
+//  Copyright (c) 2019 Marco Pacini
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy
+//  of this software and associated documentation files (the "Software"), to deal
+//  in the Software without restriction, including without limitation the rights
+//  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//  copies of the Software, and to permit persons to whom the Software is
+//  furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all
+//  copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//  SOFTWARE.
+
+package debug
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const source = `fun add(a, b) {
+  var sum = a + b;
+  return sum;
+}
+
+var x = 1;
+print add(x, 2);
+`
+
+// TestAdapter plays a session as an editor would: it stops at a breakpoint,
+// looks at the paused program and lets it run to the end.
+func TestAdapter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "debug")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	program := filepath.Join(dir, "add.lox")
+	if err := ioutil.WriteFile(program, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	inReader, in := io.Pipe()
+	out, outWriter := io.Pipe()
+
+	done := make(chan error)
+	go func() {
+		done <- NewAdapter(inReader, outWriter).Run()
+	}()
+
+	messages := make(chan string)
+	go func() {
+		r := bufio.NewReader(out)
+		for {
+			header, err := textproto.NewReader(r).ReadMIMEHeader()
+			if err != nil {
+				close(messages)
+				return
+			}
+
+			length, _ := strconv.Atoi(header.Get("Content-Length"))
+			content := make([]byte, length)
+			io.ReadFull(r, content)
+
+			// normalize the order of the fields
+			var message interface{}
+			json.Unmarshal(content, &message)
+			normalized, _ := json.Marshal(message)
+
+			messages <- string(normalized)
+		}
+	}()
+
+	seq := 0
+	send := func(command, arguments string) {
+		seq++
+		r := fmt.Sprintf(`{"seq": %d, "type": "request", "command": %q, "arguments": %s}`, seq, command, arguments)
+		fmt.Fprintf(in, "Content-Length: %d\r\n\r\n%s", len(r), r)
+	}
+
+	// expect skips the messages up to the one containing want
+	expect := func(want string) {
+		t.Helper()
+
+		for {
+			select {
+			case m, ok := <-messages:
+				if !ok {
+					t.Fatalf("want %s, got end of output", want)
+				}
+
+				if strings.Contains(m, want) {
+					return
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("want %s, got nothing", want)
+			}
+		}
+	}
+
+	path, _ := json.Marshal(program)
+
+	send("initialize", `{"adapterID": "lox"}`)
+	expect(`"supportsConfigurationDoneRequest":true`)
+	expect(`"event":"initialized"`)
+
+	send("launch", `{"program": `+string(path)+`}`)
+	expect(`"command":"launch","request_seq":2,"seq":3,"success":true`)
+
+	send("setBreakpoints", `{"source": {"path": `+string(path)+`}, "breakpoints": [{"line": 3}]}`)
+	expect(`"breakpoints":[{"line":3,"verified":true}]`)
+
+	send("configurationDone", `{}`)
+	expect(`"reason":"breakpoint"`)
+
+	send("stackTrace", `{"threadId": 1}`)
+	expect(`"stackFrames":[{"column":1,"id":0,"line":3,"name":"add"`)
+
+	send("variables", `{"variablesReference": 1}`)
+	expect(`"variables":[{"name":"a","value":"1","variablesReference":0},{"name":"b","value":"2","variablesReference":0},{"name":"sum","value":"3","variablesReference":0}]`)
+
+	send("evaluate", `{"expression": "sum * 10", "frameId": 0}`)
+	expect(`"result":"30"`)
+
+	send("evaluate", `{"expression": "nope", "frameId": 0}`)
+	expect(`"message":"error at line 1, column 1: undefined variable nope"`)
+
+	send("continue", `{"threadId": 1}`)
+	expect(`"output":"3\n"`)
+	expect(`"event":"terminated"`)
+
+	send("disconnect", `{}`)
+	expect(`"command":"disconnect"`)
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,164 @@
+//  MIT License
+//
+//  Copyright (c) 2019 Marco Pacini
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy
+//  of this software and associated documentation files (the "Software"), to deal
+//  in the Software without restriction, including without limitation the rights
+//  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//  copies of the Software, and to permit persons to whom the Software is
+//  furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all
+//  copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//  SOFTWARE.
+
+package debug
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/marcopacini/go-lox/ast"
+)
+
+const help = `commands:
+  break LINE     pause when LINE is reached (b)
+  clear LINE     remove the breakpoint at LINE
+  step           run to the next statement, entering calls (s)
+  next           run to the next statement, over calls (n)
+  out            run until the current function returns (o)
+  continue       run to the next breakpoint (c)
+  print EXPR     evaluate EXPR where the program is paused (p)
+  locals         show the variables of the innermost scope (l)
+  env            show the variables of every scope (e)
+  where          show the call stack (w)
+  quit           stop the program (q)`
+
+// Console is a Debugger driven by commands read from a terminal. It pauses
+// at the first statement of the program.
+type Console struct {
+	*Stepper
+	// Source is the text of the program, shown where it is paused.
+	Source string
+
+	in  *bufio.Reader
+	out io.Writer
+}
+
+func NewConsole(path, source string, in io.Reader, out io.Writer) *Console {
+	return &Console{NewStepper(path, true), source, bufio.NewReader(in), out}
+}
+
+func (c *Console) Before(i *ast.Interpreter, stmt ast.Stmt) error {
+	if _, ok := c.Stop(i, stmt); !ok {
+		return nil
+	}
+
+	c.show(i, stmt)
+
+	for {
+		fmt.Fprint(c.out, "(debug) ")
+
+		line, err := c.in.ReadString('\n')
+		if err != nil && line == "" {
+			fmt.Fprintln(c.out)
+			return ErrQuit
+		}
+
+		command, argument := line, ""
+		if fields := strings.Fields(line); len(fields) > 0 {
+			command = fields[0]
+			argument = strings.TrimSpace(strings.TrimSpace(line)[len(command):])
+		}
+
+		switch strings.TrimSpace(command) {
+		case "":
+		case "break", "b", "clear":
+			n, err := strconv.Atoi(argument)
+			if err != nil || n < 1 {
+				fmt.Fprintf(c.out, "invalid line %q\n", argument)
+				break
+			}
+
+			c.SetBreakpoint(n, command != "clear")
+			fmt.Fprintf(c.out, "breakpoints: %v\n", c.Breakpoints())
+		case "step", "s":
+			c.StepIn()
+			return nil
+		case "next", "n":
+			c.StepOver(i)
+			return nil
+		case "out", "o":
+			c.StepOut(i)
+			return nil
+		case "continue", "c":
+			c.Continue()
+			return nil
+		case "print", "p":
+			l, err := i.Eval(argument)
+			if err != nil {
+				fmt.Fprintln(c.out, err)
+				break
+			}
+
+			fmt.Fprintln(c.out, repr(l))
+		case "locals", "l":
+			c.scope(i.Scopes()[0])
+		case "env", "e":
+			scopes := i.Scopes()
+			for j, scope := range scopes {
+				if j == len(scopes)-1 {
+					fmt.Fprintln(c.out, "globals:")
+				} else {
+					fmt.Fprintf(c.out, "scope %d:\n", j)
+				}
+
+				c.scope(scope)
+			}
+		case "where", "w":
+			for j, f := range frames(i, stmt) {
+				fmt.Fprintf(c.out, "#%d %s at line %d\n", j, f.name, f.line)
+			}
+		case "quit", "q":
+			return ErrQuit
+		case "help", "h":
+			fmt.Fprintln(c.out, help)
+		default:
+			fmt.Fprintf(c.out, "unknown command %q, type help for a list\n", strings.TrimSpace(command))
+		}
+	}
+}
+
+// show prints the line where the program is paused.
+func (c *Console) show(i *ast.Interpreter, stmt ast.Stmt) {
+	line := ast.StmtSpan(stmt).Line
+
+	if i.File() != c.Path {
+		fmt.Fprintf(c.out, "%s:%d\n", i.File(), line)
+		return
+	}
+
+	text := ""
+	if lines := strings.Split(c.Source, "\n"); line <= len(lines) {
+		text = strings.TrimRight(lines[line-1], "\r")
+	}
+
+	fmt.Fprintf(c.out, "%d | %s\n", line, text)
+}
+
+func (c *Console) scope(scope map[string]ast.Literal) {
+	for _, name := range names(scope) {
+		fmt.Fprintf(c.out, "  %s = %s\n", name, repr(scope[name]))
+	}
+}
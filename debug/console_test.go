@@ -0,0 +1,118 @@
+//  MIT License
+//
+//  Copyright (c) 2019 Marco Pacini
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy
+//  of this software and associated documentation files (the "Software"), to deal
+//  in the Software without restriction, including without limitation the rights
+//  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//  copies of the Software, and to permit persons to whom the Software is
+//  furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all
+//  copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//  SOFTWARE.
+
+package debug
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/marcopacini/go-lox/ast"
+)
+
+func TestConsole(t *testing.T) {
+	tokens, err := (&ast.Scanner{Text: source}).Scan()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stmts, err := (&ast.Parser{Tokens: tokens}).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commands := []string{"n", "n", "s", "p a + b", "n", "where", "o"}
+
+	var out, stdout bytes.Buffer
+
+	i := ast.NewInterpreter()
+	i.Stdout = &stdout
+	i.Debugger = NewConsole("", source, strings.NewReader(strings.Join(commands, "\n")), &out)
+
+	if err := i.Run(stmts); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `1 | fun add(a, b) {
+(debug) 6 | var x = 1;
+(debug) 7 | print add(x, 2);
+(debug) 2 |   var sum = a + b;
+(debug) 3
+(debug) 3 |   return sum;
+(debug) #0 add at line 3
+#1 script at line 7
+(debug) `
+
+	if got := out.String(); got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+
+	if stdout.String() != "3\n" {
+		t.Errorf("want output %q, got %q", "3\n", stdout.String())
+	}
+}
+
+// TestConsole_Loop stops at a breakpoint in a loop on each iteration.
+func TestConsole_Loop(t *testing.T) {
+	source := "var i = 0;\nwhile (i < 3) {\n  i = i + 1;\n}\nprint i;\n"
+
+	tokens, err := (&ast.Scanner{Text: source}).Scan()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stmts, err := (&ast.Parser{Tokens: tokens}).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commands := []string{"b 3", "c", "p i", "c", "p i", "c", "p i", "c"}
+
+	var out, stdout bytes.Buffer
+
+	i := ast.NewInterpreter()
+	i.Stdout = &stdout
+	i.Debugger = NewConsole("", source, strings.NewReader(strings.Join(commands, "\n")), &out)
+
+	if err := i.Run(stmts); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `1 | var i = 0;
+(debug) breakpoints: [3]
+(debug) 3 |   i = i + 1;
+(debug) 0
+(debug) 3 |   i = i + 1;
+(debug) 1
+(debug) 3 |   i = i + 1;
+(debug) 2
+(debug) `
+
+	if got := out.String(); got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+
+	if stdout.String() != "3\n" {
+		t.Errorf("want output %q, got %q", "3\n", stdout.String())
+	}
+}
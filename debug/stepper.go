@@ -0,0 +1,214 @@
+//  MIT License
+//
+//  Copyright (c) 2019 Marco Pacini
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy
+//  of this software and associated documentation files (the "Software"), to deal
+//  in the Software without restriction, including without limitation the rights
+//  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//  copies of the Software, and to permit persons to whom the Software is
+//  furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all
+//  copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//  SOFTWARE.
+
+// Package debug pauses programs run by the tree-walking interpreter, either
+// from a terminal or from an editor speaking the Debug Adapter Protocol.
+package debug
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/marcopacini/go-lox/ast"
+)
+
+// ErrQuit stops a program the user asked to quit from a pause.
+var ErrQuit = errors.New("quit")
+
+type mode int
+
+const (
+	running  mode = iota // until a breakpoint
+	stepIn               // at the next statement
+	stepOver             // at the next statement of the same or an outer call
+	stepOut              // at the next statement of an outer call
+)
+
+// Stepper decides where a program pauses: at its breakpoints, and after the
+// step commands. Breakpoints are lines of the program file; a line pauses
+// the program when it is entered, not at each of its statements. A loop or a
+// call running a line again enters it again.
+type Stepper struct {
+	Path string
+
+	mu          sync.Mutex
+	breakpoints map[int]bool
+	mode        mode
+	depth       int
+	// the line and the offset of the last statement run in the program file
+	line, offset int
+}
+
+// NewStepper returns a stepper for the program at path, pausing at its first
+// statement if entry is true.
+func NewStepper(path string, entry bool) *Stepper {
+	s := &Stepper{Path: path, breakpoints: make(map[int]bool)}
+	if entry {
+		s.mode = stepIn
+	}
+
+	return s
+}
+
+// Stop reports whether the program must pause before stmt, and why.
+func (s *Stepper) Stop(i *ast.Interpreter, stmt ast.Stmt) (string, bool) {
+	// the statements of a block are paused at one by one
+	if _, ok := stmt.(ast.Block); ok {
+		return "", false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	span := ast.StmtSpan(stmt)
+	line := span.Line
+
+	// going back to a statement already run means a loop or a call runs
+	// the line again
+	entered := false
+	if i.File() == s.Path {
+		entered = line != s.line || span.Offset <= s.offset
+		s.line, s.offset = line, span.Offset
+	}
+
+	switch {
+	case s.mode == stepIn,
+		s.mode == stepOver && i.Depth() <= s.depth,
+		s.mode == stepOut && i.Depth() < s.depth:
+		s.mode = running
+		return "step", true
+	case entered && s.breakpoints[line]:
+		s.mode = running
+		return "breakpoint", true
+	}
+
+	return "", false
+}
+
+// SetBreakpoints replaces the breakpoints with the given lines.
+func (s *Stepper) SetBreakpoints(lines []int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.breakpoints = make(map[int]bool)
+	for _, line := range lines {
+		s.breakpoints[line] = true
+	}
+}
+
+// SetBreakpoint adds a breakpoint at line, or removes it if set is false.
+func (s *Stepper) SetBreakpoint(line int, set bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if set {
+		s.breakpoints[line] = true
+	} else {
+		delete(s.breakpoints, line)
+	}
+}
+
+// Breakpoints returns the lines of the breakpoints in order.
+func (s *Stepper) Breakpoints() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var lines []int
+	for line := range s.breakpoints {
+		lines = append(lines, line)
+	}
+
+	sort.Ints(lines)
+
+	return lines
+}
+
+// Continue runs the program up to the next breakpoint.
+func (s *Stepper) Continue() {
+	s.resume(running, 0)
+}
+
+// StepIn pauses at the next statement, entering the functions called.
+func (s *Stepper) StepIn() {
+	s.resume(stepIn, 0)
+}
+
+// StepOver pauses at the next statement, running the functions called
+// without pausing in them.
+func (s *Stepper) StepOver(i *ast.Interpreter) {
+	s.resume(stepOver, i.Depth())
+}
+
+// StepOut pauses once the function being run has returned.
+func (s *Stepper) StepOut(i *ast.Interpreter) {
+	s.resume(stepOut, i.Depth())
+}
+
+func (s *Stepper) resume(m mode, depth int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.mode, s.depth = m, depth
+}
+
+// frame is a function on the call stack, with the line being run in it.
+type frame struct {
+	name string
+	line int
+}
+
+// frames returns the call stack of a program paused before stmt, the
+// innermost function first. Each caller is at the line of its call.
+func frames(i *ast.Interpreter, stmt ast.Stmt) []frame {
+	calls := i.Calls()
+
+	frames := []frame{{"script", ast.StmtSpan(stmt).Line}}
+	for j, call := range calls {
+		frames[j].name = call.Function
+		frames = append(frames, frame{"script", call.Line})
+	}
+
+	return frames
+}
+
+// repr formats a value as it is written in code, quoting strings.
+func repr(l ast.Literal) string {
+	if s, ok := l.Value.(string); ok {
+		return strconv.Quote(s)
+	}
+
+	return l.String()
+}
+
+// names returns the names of scope in order.
+func names(scope map[string]ast.Literal) []string {
+	names := make([]string, 0, len(scope))
+	for name := range scope {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/marcopacini/go-lox/format"
+	"io/ioutil"
+	"os"
+)
+
+// runFormat runs the fmt command on args and returns the exit status. It
+// formats the files and directories named in args, or standard input if
+// there are none.
+func runFormat(args []string) int {
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	write := flags.Bool("w", false, "write the result to the source file instead of standard output")
+	diff := flags.Bool("d", false, "display diffs instead of the formatted source")
+
+	if err := flags.Parse(args); err != nil {
+		return 64
+	}
+
+	if flags.NArg() == 0 {
+		if *write {
+			fmt.Fprintln(os.Stderr, "cannot use -w with standard input")
+			return 64
+		}
+
+		b, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitNoInput
+		}
+
+		return formatFile("<standard input>", string(b), 0, false, *diff)
+	}
+
+	return forEachFile(flags.Args(), func(path string, info os.FileInfo) int {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitNoInput
+		}
+
+		return formatFile(path, string(b), info.Mode().Perm(), *write, *diff)
+	})
+}
+
+// formatFile formats src, the content of the file name, and either prints the
+// result or writes it back to the file. With diff, the changes are printed.
+func formatFile(name string, src string, perm os.FileMode, write bool, diff bool) int {
+	out, err := format.Source(src)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: ", name)
+		report(src, err)
+		return exitData
+	}
+
+	if diff {
+		fmt.Print(format.Diff(name, src, out))
+	}
+
+	if write && out != src {
+		if err := ioutil.WriteFile(name, []byte(out), perm); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitCantCreate
+		}
+	}
+
+	if !write && !diff {
+		fmt.Print(out)
+	}
+
+	return 0
+}
@@ -0,0 +1,169 @@
+//  MIT License
+//
+//  Copyright (c) 2019 Marco Pacini
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy
+//  of this software and associated documentation files (the "Software"), to deal
+//  in the Software without restriction, including without limitation the rights
+//  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//  copies of the Software, and to permit persons to whom the Software is
+//  furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all
+//  copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//  SOFTWARE.
+
+package format
+
+import (
+	"fmt"
+	"strings"
+)
+
+// context is the number of unchanged lines shown around a change.
+const context = 3
+
+type edit struct {
+	op   byte // ' ', '-' or '+'
+	line string
+	// a and b are the indexes of the line in the old and the new text
+	a, b int
+}
+
+// Diff returns the changes from old to new, both the content of the file
+// name, in unified format. It is empty if there is no change.
+func Diff(name, old, new string) string {
+	if old == new {
+		return ""
+	}
+
+	edits := diffLines(splitLines(old), splitLines(new))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s.orig\n+++ %s\n", name, name)
+
+	for start := 0; start < len(edits); {
+		for start < len(edits) && edits[start].op == ' ' {
+			start++
+		}
+
+		if start == len(edits) {
+			break
+		}
+
+		// the hunk goes on while changes are close enough to share context
+		end, unchanged := start, 0
+		for j := start; j < len(edits) && unchanged <= 2*context; j++ {
+			if edits[j].op == ' ' {
+				unchanged++
+			} else {
+				end, unchanged = j+1, 0
+			}
+		}
+
+		from := start - context
+		if from < 0 {
+			from = 0
+		}
+
+		to := end + context
+		if to > len(edits) {
+			to = len(edits)
+		}
+
+		writeHunk(&b, edits[from:to])
+		start = to
+	}
+
+	return b.String()
+}
+
+func writeHunk(b *strings.Builder, edits []edit) {
+	var oldLen, newLen int
+	for _, e := range edits {
+		if e.op != '+' {
+			oldLen++
+		}
+		if e.op != '-' {
+			newLen++
+		}
+	}
+
+	// an empty range starts at the line before it
+	oldStart, newStart := edits[0].a, edits[0].b
+	if oldLen > 0 {
+		oldStart++
+	}
+	if newLen > 0 {
+		newStart++
+	}
+
+	fmt.Fprintf(b, "@@ -%d,%d +%d,%d @@\n", oldStart, oldLen, newStart, newLen)
+
+	for _, e := range edits {
+		b.WriteByte(e.op)
+		b.WriteString(e.line)
+
+		if !strings.HasSuffix(e.line, "\n") {
+			b.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+// splitLines splits s after each newline.
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines
+}
+
+// diffLines computes the shortest edit script from a to b from the longest
+// common subsequence of their lines.
+func diffLines(a, b []string) []edit {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:], b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var edits []edit
+
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			edits = append(edits, edit{' ', a[i], i, j})
+			i, j = i+1, j+1
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			edits = append(edits, edit{'-', a[i], i, j})
+			i++
+		default:
+			edits = append(edits, edit{'+', b[j], i, j})
+			j++
+		}
+	}
+
+	return edits
+}
@@ -0,0 +1,502 @@
+//  MIT License
+//
+//  Copyright (c) 2019 Marco Pacini
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy
+//  of this software and associated documentation files (the "Software"), to deal
+//  in the Software without restriction, including without limitation the rights
+//  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//  copies of the Software, and to permit persons to whom the Software is
+//  furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all
+//  copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//  SOFTWARE.
+
+// Package format prints Lox source code in its canonical style: two spaces of
+// indentation, braces on the line that opens them, single spaces around binary
+// operators and at most one blank line between statements.
+package format
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/marcopacini/go-lox/ast"
+)
+
+// Source returns src formatted. Comments are kept: those on the line where a
+// statement ends stay at its end, any other precedes the statement that
+// follows it. An expression or statement with a comment between its tokens
+// is printed as written. Source fails if src has syntax errors.
+func Source(src string) (string, error) {
+	scanner := ast.Scanner{Text: src}
+	tokens, err := scanner.Scan()
+	if err != nil {
+		return "", err
+	}
+
+	parser := ast.Parser{Tokens: tokens}
+	stmts, err := parser.Parse()
+	if err != nil {
+		return "", err
+	}
+
+	p := printer{src: src, opened: true}
+
+	for _, t := range tokens {
+		p.comments = append(p.comments, t.Comments...)
+	}
+
+	for offset, c := range src {
+		if c == '\n' {
+			p.lines = append(p.lines, offset)
+		}
+	}
+
+	for _, stmt := range stmts {
+		p.stmt(stmt)
+	}
+
+	p.flush(len(src) + 1)
+
+	return string(p.out), nil
+}
+
+type printer struct {
+	src string
+	out []byte
+
+	indent   int
+	comments []ast.Comment
+	// offsets of the newlines in src
+	lines []int
+
+	// last is the source line of the last statement or comment printed,
+	// opened is set until something is printed in the current block.
+	last   int
+	opened bool
+}
+
+func (p *printer) write(s ...string) {
+	for _, s := range s {
+		p.out = append(p.out, s...)
+	}
+}
+
+func (p *printer) newline() {
+	p.out = append(p.out, '\n')
+}
+
+func (p *printer) writeIndent() {
+	p.write(strings.Repeat("  ", p.indent))
+}
+
+// lineAt returns the source line of offset.
+func (p *printer) lineAt(offset int) int {
+	return sort.SearchInts(p.lines, offset) + 1
+}
+
+func end(span ast.Span) int {
+	return span.Offset + span.Length
+}
+
+// begin prepares a line for an item starting at line, keeping one blank line
+// if the source had any before it.
+func (p *printer) begin(line int) {
+	if !p.opened && line > p.last+1 {
+		p.newline()
+	}
+
+	p.opened = false
+	p.writeIndent()
+}
+
+// flush prints the comments found before limit.
+func (p *printer) flush(limit int) {
+	for len(p.comments) > 0 && p.comments[0].Offset < limit {
+		c := p.comments[0]
+		p.comments = p.comments[1:]
+
+		text := strings.TrimRight(c.Text, " \t\r")
+
+		if c.Line == p.last && len(p.out) > 0 {
+			p.out = p.out[:len(p.out)-1]
+			p.write(" ", text)
+			p.newline()
+			continue
+		}
+
+		p.begin(c.Line)
+		p.write(text)
+		p.newline()
+
+		p.last = c.Line
+	}
+}
+
+// keep replaces what was printed from mark on with the source text of span if
+// a comment inside span is still to be printed, since reflowing the code would
+// take the comment away from the tokens it annotates.
+func (p *printer) keep(mark int, span ast.Span) {
+	var rest []ast.Comment
+
+	for _, c := range p.comments {
+		if c.Offset < span.Offset || c.Offset >= end(span) {
+			rest = append(rest, c)
+		}
+	}
+
+	if len(rest) == len(p.comments) {
+		return
+	}
+
+	p.out = append(p.out[:mark], p.src[span.Offset:end(span)]...)
+	p.comments = rest
+}
+
+func (p *printer) stmt(stmt ast.Stmt) {
+	span := stmtSpan(stmt)
+
+	p.flush(span.Offset)
+	p.begin(span.Line)
+
+	mark := len(p.out)
+	p.inline(stmt)
+	p.keep(mark, span)
+
+	p.newline()
+
+	p.last = p.lineAt(end(span) - 1)
+}
+
+// block prints stmts between braces, the closing one being at offset close.
+// The opening brace is at line open.
+func (p *printer) block(stmts []ast.Stmt, open, close int) {
+	p.write("{")
+
+	if len(stmts) == 0 && (len(p.comments) == 0 || p.comments[0].Offset >= close) {
+		p.write("}")
+		return
+	}
+
+	p.newline()
+
+	p.last, p.opened = open, true
+	p.indent++
+
+	for _, stmt := range stmts {
+		p.stmt(stmt)
+	}
+
+	p.flush(close)
+
+	p.indent--
+	p.writeIndent()
+	p.write("}")
+}
+
+// body prints the statement controlled by an if, else, for or while.
+func (p *printer) body(stmt ast.Stmt) {
+	p.write(" ")
+	p.inline(stmt)
+}
+
+func (p *printer) blockStmt(b ast.Block) {
+	p.block(b.Stmts, b.Span.Line, end(b.Span)-1)
+}
+
+// inline prints stmt from the current position, with no line break after it.
+func (p *printer) inline(stmt ast.Stmt) {
+	switch s := stmt.(type) {
+	case ast.Block:
+		p.blockStmt(s)
+
+	case ast.BreakStmt:
+		p.write("break;")
+
+	case ast.ClassStmt:
+		p.write("class ", s.Name.Lexeme)
+		if s.Superclass != nil {
+			p.write(" < ", s.Superclass.Lexeme)
+		}
+		p.write(" ")
+
+		methods := make([]ast.Stmt, len(s.Methods))
+		for j, m := range s.Methods {
+			methods[j] = method{m}
+		}
+
+		p.block(methods, s.Span.Line, end(s.Span)-1)
+
+	case ast.ContinueStmt:
+		p.write("continue;")
+
+	case ast.Declaration:
+		p.write("var ", s.Lexeme)
+		if s.Expr != nil {
+			p.write(" = ")
+			p.expr(s.Expr)
+		}
+		p.write(";")
+
+	case ast.ExprStmt:
+		p.expr(s.Expr)
+		p.write(";")
+
+	case ast.ForStmt:
+		p.write("for (")
+
+		if s.Init != nil {
+			p.inline(s.Init)
+		} else {
+			p.write(";")
+		}
+
+		if s.Condition != nil {
+			p.write(" ")
+			p.expr(s.Condition)
+		}
+		p.write(";")
+
+		if s.Increment != nil {
+			p.write(" ")
+			p.expr(s.Increment)
+		}
+		p.write(")")
+
+		p.body(s.Body)
+
+	case ast.Function:
+		p.write("fun ")
+		p.function(s)
+
+	case method:
+		p.function(s.Function)
+
+	case ast.IfStmt:
+		p.write("if (")
+		p.expr(s.Condition)
+		p.write(")")
+		p.body(s.Then)
+
+		if s.Else != nil {
+			p.write(" else")
+			p.body(s.Else)
+		}
+
+	case ast.ImportStmt:
+		p.write("import ")
+		if s.Name != nil {
+			p.write(s.Name.Lexeme, " from ")
+		}
+		p.write(s.Path.Lexeme, ";")
+
+	case ast.PrintStmt:
+		p.write("print ")
+		p.expr(s.Expr)
+		p.write(";")
+
+	case ast.ReturnStmt:
+		p.write("return")
+		if s.Expr != nil {
+			p.write(" ")
+			p.expr(s.Expr)
+		}
+		p.write(";")
+
+	case ast.ThrowStmt:
+		p.write("throw ")
+		p.expr(s.Expr)
+		p.write(";")
+
+	case ast.TryStmt:
+		p.write("try ")
+		p.blockStmt(s.Body)
+
+		if s.Catch != nil {
+			p.write(" catch (", s.Name.Lexeme, ") ")
+			p.blockStmt(*s.Catch)
+		}
+
+		if s.Finally != nil {
+			p.write(" finally ")
+			p.blockStmt(*s.Finally)
+		}
+
+	case ast.WhileStmt:
+		p.write("while (")
+		p.expr(s.Condition)
+		p.write(")")
+		p.body(s.Body)
+	}
+}
+
+// function prints the parameters and the body of f.
+func (p *printer) function(f ast.Function) {
+	if !f.Anonymous() {
+		p.write(f.Name.Lexeme)
+	}
+
+	names := make([]string, len(f.Arguments))
+	for j, argument := range f.Arguments {
+		names[j] = argument.Lexeme
+	}
+
+	p.write("(", strings.Join(names, ", "), ") ")
+
+	// the body of an arrow function is a return statement at the arrow
+	if len(f.Body) == 1 {
+		if r, ok := f.Body[0].(ast.ReturnStmt); ok && r.Keyword.TokenType == ast.Arrow {
+			p.write("=> ")
+			p.expr(r.Expr)
+			return
+		}
+	}
+
+	p.block(f.Body, f.Span.Line, end(f.Span)-1)
+}
+
+func (p *printer) exprs(exprs []ast.Expr) {
+	for j, e := range exprs {
+		if j > 0 {
+			p.write(", ")
+		}
+
+		p.expr(e)
+	}
+}
+
+func (p *printer) expr(expr ast.Expr) {
+	mark := len(p.out)
+	p.reflow(expr)
+	p.keep(mark, ast.ExprSpan(expr))
+}
+
+// reflow prints expr in the canonical style.
+func (p *printer) reflow(expr ast.Expr) {
+	switch e := expr.(type) {
+	case ast.Assign:
+		p.write(e.Variable.Lexeme, " = ")
+		p.expr(e.Expr)
+
+	case ast.Binary:
+		p.expr(e.Left)
+		p.write(" ", e.Operator.Lexeme, " ")
+		p.expr(e.Right)
+
+	case ast.Call:
+		p.expr(e.Callee)
+		p.write("(")
+		p.exprs(e.Arguments)
+		p.write(")")
+
+	case ast.Get:
+		p.expr(e.Object)
+		p.write(".", e.Name.Lexeme)
+
+	case ast.Grouping:
+		p.write("(")
+		p.expr(e.Expr)
+		p.write(")")
+
+	case ast.Index:
+		p.expr(e.Object)
+		p.write("[")
+		p.expr(e.Index)
+		p.write("]")
+
+	case ast.IndexSet:
+		p.expr(e.Object)
+		p.write("[")
+		p.expr(e.Index)
+		p.write("] = ")
+		p.expr(e.Value)
+
+	case ast.Lambda:
+		p.write("fun ")
+		p.function(e.Function)
+
+	case ast.ListExpr:
+		p.write("[")
+		p.exprs(e.Elements)
+		p.write("]")
+
+	case ast.Literal:
+		switch e.Value.(type) {
+		case float64, string:
+			// as written, since numbers and strings have several spellings
+			p.write(p.src[e.Span.Offset:end(e.Span)])
+		default:
+			p.write(e.String())
+		}
+
+	case ast.Logical:
+		p.expr(e.Left)
+		p.write(" ", e.Operator.Lexeme, " ")
+		p.expr(e.Right)
+
+	case ast.MapExpr:
+		p.write("{")
+		for j := range e.Keys {
+			if j > 0 {
+				p.write(", ")
+			}
+
+			p.expr(e.Keys[j])
+			p.write(": ")
+			p.expr(e.Values[j])
+		}
+		p.write("}")
+
+	case ast.Set:
+		p.expr(e.Object)
+		p.write(".", e.Name.Lexeme, " = ")
+		p.expr(e.Value)
+
+	case ast.Slice:
+		p.expr(e.Object)
+		p.write("[")
+		if e.Start != nil {
+			p.expr(e.Start)
+		}
+		p.write(":")
+		if e.End != nil {
+			p.expr(e.End)
+		}
+		p.write("]")
+
+	case ast.SuperExpr:
+		p.write("super.", e.Method.Lexeme)
+
+	case ast.ThisExpr:
+		p.write("this")
+
+	case ast.Unary:
+		p.write(e.Operator.Lexeme)
+		p.expr(e.Right)
+
+	case ast.Variable:
+		p.write(e.Lexeme)
+	}
+}
+
+// method is a function declared in a class, printed without the fun keyword.
+type method struct {
+	ast.Function
+}
+
+func stmtSpan(stmt ast.Stmt) ast.Span {
+	if m, ok := stmt.(method); ok {
+		return m.Span
+	}
+
+	return ast.StmtSpan(stmt)
+}
@@ -0,0 +1,108 @@
+//  MIT License
+//
+//  Copyright (c) 2019 Marco Pacini
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy
+//  of this software and associated documentation files (the "Software"), to deal
+//  in the Software without restriction, including without limitation the rights
+//  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//  copies of the Software, and to permit persons to whom the Software is
+//  furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all
+//  copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//  SOFTWARE.
+
+package format
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSource(t *testing.T) {
+	table := []struct {
+		in  string
+		out string
+	}{
+		{"var   a=1+2*3;", "var a = 1 + 2 * 3;\n"},
+		{"print -(1.50);print !true and nil;", "print -(1.50);\nprint !true and nil;\n"},
+		{"fun f(a,b){return a;}", "fun f(a, b) {\n  return a;\n}\n"},
+		{"if (x) print 1; else { }", "if (x) print 1; else {}\n"},
+		{"for(var i=0;i<3;i=i+1){}\nfor(;;)break;", "for (var i = 0; i < 3; i = i + 1) {}\nfor (;;) break;\n"},
+		{"class A<B{init(x){this.x=x;}}", "class A < B {\n  init(x) {\n    this.x = x;\n  }\n}\n"},
+		{"var f=fun(x)=>x*2;var g=fun(){};", "var f = fun (x) => x * 2;\nvar g = fun () {};\n"},
+		{"xs[1:]=={\"a\":[1,2]};", "xs[1:] == {\"a\": [1, 2]};\n"},
+		{"try{throw 1;}catch(e){}finally{}", "try {\n  throw 1;\n} catch (e) {} finally {}\n"},
+		{"import m from \"m.lox\";", "import m from \"m.lox\";\n"},
+		{"var a;\n\n\n\nvar b;", "var a;\n\nvar b;\n"},
+		{"// head\nvar a; // tail\n{ // open\n  // inner\n}\n// end", "// head\nvar a; // tail\n{ // open\n  // inner\n}\n// end\n"},
+		{"print f(1, // arg comment\n 2);", "print f(1, // arg comment\n 2);\n"},
+		{"print  1+g(h(1, // c\n 2));", "print 1 + g(h(1, // c\n 2));\n"},
+		{"if (x) // c\n  print  1;", "if (x) // c\n  print  1;\n"},
+		{"f(fun(){// c\nprint 1;});", "f(fun () { // c\n  print 1;\n});\n"},
+	}
+
+	for _, test := range table {
+		t.Run(test.in, func(t *testing.T) {
+			out, err := Source(test.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if out != test.out {
+				t.Errorf("want %q, got %q", test.out, out)
+			}
+		})
+	}
+}
+
+// TestSource_Idempotent formats the golden test programs twice.
+func TestSource_Idempotent(t *testing.T) {
+	root := filepath.Join("..", "ast", "testdata")
+
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || filepath.Ext(path) != ".lox" {
+			return err
+		}
+
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		once, err := Source(string(b))
+		if err != nil {
+			return nil // programs with syntax errors
+		}
+
+		twice, err := Source(once)
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+
+		if once != twice {
+			t.Errorf("%s: formatting is not idempotent:\n%s", path, Diff(path, once, twice))
+		}
+
+		if strings.Count(once, "//") != strings.Count(string(b), "//") {
+			t.Errorf("%s: comments were lost", path)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+}
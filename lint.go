@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"github.com/marcopacini/go-lox/ast"
+	"io/ioutil"
+	"os"
+)
+
+// runLint runs the lint command on the files and directories named in args
+// and returns the exit status, which is 1 if there are warnings.
+func runLint(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: lox lint path ...")
+		return 64
+	}
+
+	return forEachFile(args, func(path string, info os.FileInfo) int {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitNoInput
+		}
+
+		return lintFile(path, string(b))
+	})
+}
+
+func lintFile(name string, source string) int {
+	fail := func(err error) int {
+		fmt.Fprintf(os.Stderr, "%s: ", name)
+		report(source, err)
+		return exitData
+	}
+
+	s := ast.Scanner{Text: source}
+	tokens, err := s.Scan()
+	if err != nil {
+		return fail(err)
+	}
+
+	p := ast.Parser{Tokens: tokens}
+	stmts, err := p.Parse()
+	if err != nil {
+		return fail(err)
+	}
+
+	var r ast.Resolver
+	if err := r.Resolve(stmts); err != nil {
+		return fail(err)
+	}
+
+	warnings := ast.Lint(stmts)
+	for _, w := range warnings {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", name, w.Render(source))
+	}
+
+	if len(warnings) > 0 {
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,173 @@
+//  MIT License
+//
+//  Copyright (c) 2019 Marco Pacini
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy
+//  of this software and associated documentation files (the "Software"), to deal
+//  in the Software without restriction, including without limitation the rights
+//  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//  copies of the Software, and to permit persons to whom the Software is
+//  furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all
+//  copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//  SOFTWARE.
+
+// Package lox embeds a Lox interpreter in a Go program. The host can define
+// global values, register Go functions as natives and evaluate source code
+// repeatedly against the same global state.
+package lox
+
+import (
+	"fmt"
+	"github.com/marcopacini/go-lox/ast"
+	"io"
+)
+
+type Interpreter struct {
+	interpreter *ast.Interpreter
+}
+
+func New() *Interpreter {
+	return &Interpreter{ast.NewInterpreter()}
+}
+
+// Eval runs source and returns the value of its last statement, when it is an
+// expression, converted to a Go value; otherwise it returns nil.
+func (l *Interpreter) Eval(source string) (interface{}, error) {
+	scanner := ast.Scanner{Text: source}
+
+	tokens, err := scanner.Scan()
+	if err != nil {
+		return nil, err
+	}
+
+	parser := ast.Parser{Tokens: tokens}
+
+	stmts, err := parser.Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	l.interpreter.Literal = ast.Literal{}
+
+	if err := l.interpreter.Run(stmts); err != nil {
+		return nil, err
+	}
+
+	if len(stmts) == 0 {
+		return nil, nil
+	}
+
+	if _, ok := stmts[len(stmts)-1].(ast.ExprStmt); !ok {
+		return nil, nil
+	}
+
+	return fromLox(l.interpreter.Literal.Value), nil
+}
+
+// SetOutput redirects the output of print and of the natives writing to the
+// standard output and error.
+func (l *Interpreter) SetOutput(stdout io.Writer, stderr io.Writer) {
+	l.interpreter.Stdout, l.interpreter.Stderr = stdout, stderr
+}
+
+// SetInput sets the reader used as standard input by the natives.
+func (l *Interpreter) SetInput(stdin io.Reader) {
+	l.interpreter.Stdin = stdin
+}
+
+// Define sets the global variable name to value, converted to a Lox value.
+// Go functions are registered as natives, see Register.
+func (l *Interpreter) Define(name string, value interface{}) error {
+	v, err := toLox(name, value)
+	if err != nil {
+		return err
+	}
+
+	l.interpreter.Define(name, v)
+
+	return nil
+}
+
+// Register exposes the Go function fn to Lox as the global function name.
+//
+// Arguments are converted to the types of the parameters of fn: Lox numbers
+// can be passed as any Go numeric type, as long as they fit, strings as string
+// and booleans as bool; an interface{} parameter receives the Go value of the
+// argument, see Eval. fn may return no value, a single value, an error, or a
+// value followed by an error, which is reported as a Lox runtime error.
+func (l *Interpreter) Register(name string, fn interface{}) error {
+	native, err := newNative(name, fn)
+	if err != nil {
+		return err
+	}
+
+	l.interpreter.Define(name, native)
+
+	return nil
+}
+
+// lookup returns the value of the global variable name, which can be defined
+// by the program or by the host.
+func (l *Interpreter) lookup(name string) (ast.Literal, error) {
+	for e := l.interpreter.Globals; e != nil; e = e.Parent {
+		if value, ok := e.Scope[name]; ok {
+			return value.(ast.Literal), nil
+		}
+	}
+
+	return ast.Literal{}, fmt.Errorf("undefined variable %v", name)
+}
+
+// Get returns the value of the global variable name.
+func (l *Interpreter) Get(name string) (interface{}, error) {
+	value, err := l.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return fromLox(value.Value), nil
+}
+
+// Call calls the global function name with the given arguments, converted to
+// Lox values, and returns its result.
+func (l *Interpreter) Call(name string, arguments ...interface{}) (interface{}, error) {
+	value, err := l.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+
+	f, ok := value.Value.(ast.Callable)
+	if !ok {
+		return nil, fmt.Errorf("%v is not a function", name)
+	}
+
+	if f.Arity() >= 0 && f.Arity() != len(arguments) {
+		return nil, fmt.Errorf("expected %d arguments but got %d", f.Arity(), len(arguments))
+	}
+
+	literals := make([]ast.Expr, len(arguments))
+	for i, argument := range arguments {
+		v, err := toLox(fmt.Sprintf("%s argument %d", name, i+1), argument)
+		if err != nil {
+			return nil, err
+		}
+
+		literals[i] = ast.Literal{Value: v}
+	}
+
+	result, err := f.Call(l.interpreter, literals)
+	if err != nil {
+		return nil, err
+	}
+
+	return fromLox(result.Value), nil
+}
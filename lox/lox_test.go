@@ -0,0 +1,201 @@
+//  MIT License
+//
+//  Copyright (c) 2019 Marco Pacini
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy
+//  of this software and associated documentation files (the "Software"), to deal
+//  in the Software without restriction, including without limitation the rights
+//  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//  copies of the Software, and to permit persons to whom the Software is
+//  furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all
+//  copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//  SOFTWARE.
+
+package lox
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestInterpreter_Eval(t *testing.T) {
+	l := New()
+
+	table := []struct {
+		in  string
+		out interface{}
+	}{
+		{"var a = 1;", nil},
+		{"a + 1;", 2.0},
+		{"fun twice(x) { return x * 2; }", nil},
+		{"twice(a) == 2;", true},
+		{"class A { init(n) { this.n = n; } }", nil},
+		{"A(\"x\").n;", "x"},
+		{"print a;", nil},
+		{"var xs = [1, 2, 3];", nil},
+		{"xs[1] = 5;", 5.0},
+		{"xs[-1];", 3.0},
+		{"len(xs[1:]);", 2.0},
+		{"xs == [1, 5, 3];", true},
+		{"pop(xs) + len(xs);", 5.0},
+		{"var m = {\"a\": 1, 2: xs};", nil},
+		{"m[\"b\"] = m[2][0];", 1.0},
+		{"len(keys(m));", 3.0},
+		{"has(m, 3);", false},
+		{"max(sqrt(16), 2 ** 3 % 5, floor(pi));", 4.0},
+		{"join(split(upper(\"a-b\"), \"-\"), \"+\");", "A+B"},
+		{"format(\"{}:{}\", substr(\"héllo\", 1, 3), \"héllo\"[-1]);", "él:o"},
+	}
+
+	for _, test := range table {
+		out, err := l.Eval(test.in)
+		if err != nil {
+			t.Fatalf("%s: %v", test.in, err)
+		}
+
+		if out != test.out {
+			t.Errorf("%s: want %v, got %v", test.in, test.out, out)
+		}
+	}
+}
+
+func TestInterpreter_Register(t *testing.T) {
+	l := New()
+
+	if err := l.Define("greeting", "hello"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := l.Define("limit", 3); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := l.Register("repeat", strings.Repeat); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := l.Register("join", strings.Join); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := l.Register("sum", func(values ...float64) float64 {
+		total := 0.0
+		for _, v := range values {
+			total += v
+		}
+		return total
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := l.Register("toInt64", func(n int64) int64 { return n }); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := l.Register("toUint8", func(n uint8) uint8 { return n }); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := l.Register("fail", func() (bool, error) {
+		return false, errors.New("failure")
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	table := []struct {
+		in  string
+		out interface{}
+		err string
+	}{
+		{"repeat(greeting, limit);", "hellohellohello", ""},
+		{"sum(1, 2, 3, limit);", 9.0, ""},
+		{"sum();", 0.0, ""},
+		{"join([\"a\", greeting], \"-\");", "a-hello", ""},
+		{"repeat(greeting, 1.5);", nil, "error at line 1, column 1: repeat: argument 2: expected int, got 1.5"},
+		{"repeat(1, 2);", nil, "error at line 1, column 1: repeat: argument 1: expected string, got number"},
+		{"fail();", nil, "error at line 1, column 1: failure"},
+		{"toInt64(-2 ** 63);", -9223372036854775808.0, ""},
+		{"toInt64(10 ** 30);", nil, "error at line 1, column 1: toInt64: argument 1: expected int64, got 1e+30"},
+		{"toInt64(2 ** 63);", nil, "error at line 1, column 1: toInt64: argument 1: expected int64, got 9.223372036854776e+18"},
+		{"toInt64(inf);", nil, "error at line 1, column 1: toInt64: argument 1: expected int64, got inf"},
+		{"toInt64(nan);", nil, "error at line 1, column 1: toInt64: argument 1: expected int64, got nan"},
+		{"toUint8(255);", 255.0, ""},
+		{"toUint8(256);", nil, "error at line 1, column 1: toUint8: argument 1: expected uint8, got 256"},
+		{"toUint8(-1);", nil, "error at line 1, column 1: toUint8: argument 1: expected uint8, got -1"},
+	}
+
+	for _, test := range table {
+		out, err := l.Eval(test.in)
+
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("%s: want error %q, got %v", test.in, test.err, err)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("%s: %v", test.in, err)
+		}
+
+		if out != test.out {
+			t.Errorf("%s: want %v, got %v", test.in, test.out, out)
+		}
+	}
+}
+
+func TestInterpreter_Call(t *testing.T) {
+	l := New()
+
+	if _, err := l.Eval("fun add(a, b) { return a + b; } var n = 42;"); err != nil {
+		t.Fatal(err)
+	}
+
+	if out, err := l.Call("add", 1, 2); err != nil || out != 3.0 {
+		t.Errorf("want 3, got %v (%v)", out, err)
+	}
+
+	if out, err := l.Get("n"); err != nil || out != 42.0 {
+		t.Errorf("want 42, got %v (%v)", out, err)
+	}
+
+	if _, err := l.Call("n"); err == nil {
+		t.Errorf("want error calling a number")
+	}
+
+	if err := l.Register("bad", 1); err == nil {
+		t.Errorf("want error registering a number")
+	}
+}
+
+func TestInterpreter_SetOutput(t *testing.T) {
+	l := New()
+
+	var stdout, stderr bytes.Buffer
+	l.SetOutput(&stdout, &stderr)
+	l.SetInput(strings.NewReader("first\nsecond\n"))
+
+	if _, err := l.Eval("print readLine(); printErr(readLine()); print readLine();"); err != nil {
+		t.Fatal(err)
+	}
+
+	if stdout.String() != "first\nnil\n" {
+		t.Errorf("want stdout %q, got %q", "first\nnil\n", stdout.String())
+	}
+
+	if stderr.String() != "second\n" {
+		t.Errorf("want stderr %q, got %q", "second\n", stderr.String())
+	}
+}
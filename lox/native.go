@@ -0,0 +1,330 @@
+//  MIT License
+//
+//  Copyright (c) 2019 Marco Pacini
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy
+//  of this software and associated documentation files (the "Software"), to deal
+//  in the Software without restriction, including without limitation the rights
+//  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//  copies of the Software, and to permit persons to whom the Software is
+//  furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all
+//  copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//  SOFTWARE.
+
+package lox
+
+import (
+	"fmt"
+	"github.com/marcopacini/go-lox/ast"
+	"math"
+	"reflect"
+)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+func newNative(name string, fn interface{}) (ast.Native, error) {
+	f := reflect.ValueOf(fn)
+	if f.Kind() != reflect.Func {
+		return ast.Native{}, fmt.Errorf("%s: expected a function, got %T", name, fn)
+	}
+
+	t := f.Type()
+
+	switch {
+	case t.NumOut() > 2:
+		return ast.Native{}, fmt.Errorf("%s: too many return values", name)
+	case t.NumOut() == 2 && t.Out(1) != errorType:
+		return ast.Native{}, fmt.Errorf("%s: second return value must be an error", name)
+	}
+
+	arity := t.NumIn()
+	if t.IsVariadic() {
+		arity = -1
+	}
+
+	return ast.NewNative(name, arity, func(i *ast.Interpreter, arguments []ast.Literal) (ast.Literal, error) {
+		if t.IsVariadic() && len(arguments) < t.NumIn()-1 {
+			return ast.Literal{}, fmt.Errorf("expected at least %d arguments but got %d", t.NumIn()-1, len(arguments))
+		}
+
+		in := make([]reflect.Value, len(arguments))
+
+		for j, argument := range arguments {
+			var parameter reflect.Type
+			if t.IsVariadic() && j >= t.NumIn()-1 {
+				parameter = t.In(t.NumIn() - 1).Elem()
+			} else {
+				parameter = t.In(j)
+			}
+
+			v, err := convert(argument.Value, parameter)
+			if err != nil {
+				return ast.Literal{}, fmt.Errorf("%s: argument %d: %v", name, j+1, err)
+			}
+
+			in[j] = v
+		}
+
+		out := f.Call(in)
+
+		if len(out) > 0 && t.Out(len(out)-1) == errorType {
+			if err, _ := out[len(out)-1].Interface().(error); err != nil {
+				return ast.Literal{}, err
+			}
+
+			out = out[:len(out)-1]
+		}
+
+		if len(out) == 0 {
+			return ast.Literal{Value: nil}, nil
+		}
+
+		v, err := toLox(name, out[0].Interface())
+		if err != nil {
+			return ast.Literal{}, err
+		}
+
+		return ast.Literal{Value: v}, nil
+	}), nil
+}
+
+// convert returns a Go value of type t for the Lox value v.
+func convert(v interface{}, t reflect.Type) (reflect.Value, error) {
+	mismatch := func() (reflect.Value, error) {
+		return reflect.Value{}, fmt.Errorf("expected %v, got %s", t, typeName(v))
+	}
+
+	switch t.Kind() {
+	case reflect.Float32, reflect.Float64:
+		f, ok := v.(float64)
+		if !ok {
+			return mismatch()
+		}
+
+		return reflect.ValueOf(f).Convert(t), nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		f, ok := v.(float64)
+		if !ok {
+			return mismatch()
+		}
+
+		// checked as a float, since converting it first could wrap around
+		limit := math.Ldexp(1, t.Bits()-1)
+		if f != math.Trunc(f) || f < -limit || f >= limit {
+			return reflect.Value{}, fmt.Errorf("expected %v, got %v", t, ast.Literal{Value: f})
+		}
+
+		value := reflect.New(t).Elem()
+
+		value.SetInt(int64(f))
+
+		return value, nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		f, ok := v.(float64)
+		if !ok {
+			return mismatch()
+		}
+
+		if f != math.Trunc(f) || f < 0 || f >= math.Ldexp(1, t.Bits()) {
+			return reflect.Value{}, fmt.Errorf("expected %v, got %v", t, ast.Literal{Value: f})
+		}
+
+		value := reflect.New(t).Elem()
+
+		value.SetUint(uint64(f))
+
+		return value, nil
+
+	case reflect.String:
+		s, ok := v.(string)
+		if !ok {
+			return mismatch()
+		}
+
+		return reflect.ValueOf(s).Convert(t), nil
+
+	case reflect.Bool:
+		b, ok := v.(bool)
+		if !ok {
+			return mismatch()
+		}
+
+		return reflect.ValueOf(b).Convert(t), nil
+
+	case reflect.Slice:
+		l, ok := v.(*ast.List)
+		if !ok {
+			break
+		}
+
+		value := reflect.MakeSlice(t, len(l.Elements), len(l.Elements))
+		for j, e := range l.Elements {
+			element, err := convert(e.Value, t.Elem())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+
+			value.Index(j).Set(element)
+		}
+
+		return value, nil
+
+	case reflect.Map:
+		m, ok := v.(*ast.Map)
+		if !ok {
+			break
+		}
+
+		value := reflect.MakeMapWithSize(t, m.Len())
+		values := m.Values()
+		for j, k := range m.Keys() {
+			key, err := convert(k.Value, t.Key())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+
+			element, err := convert(values[j].Value, t.Elem())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+
+			value.SetMapIndex(key, element)
+		}
+
+		return value, nil
+	}
+
+	if v == nil {
+		if t.Kind() == reflect.Interface || t.Kind() == reflect.Ptr {
+			return reflect.Zero(t), nil
+		}
+
+		return mismatch()
+	}
+
+	value := reflect.ValueOf(fromLox(v))
+	if !value.Type().AssignableTo(t) {
+		return mismatch()
+	}
+
+	return value, nil
+}
+
+// toLox returns the Lox value for the Go value v. Functions are wrapped into
+// natives called name.
+func toLox(name string, v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case nil, bool, string, float64, ast.Callable, *ast.ClassInstance, *ast.List, *ast.Map:
+		return v, nil
+	}
+
+	value := reflect.ValueOf(v)
+
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(value.Uint()), nil
+	case reflect.Float32:
+		return value.Float(), nil
+	case reflect.String:
+		return value.String(), nil
+	case reflect.Bool:
+		return value.Bool(), nil
+	case reflect.Func:
+		return newNative(name, v)
+	case reflect.Slice, reflect.Array:
+		elements := make([]ast.Literal, value.Len())
+		for j := range elements {
+			element, err := toLox(name, value.Index(j).Interface())
+			if err != nil {
+				return nil, err
+			}
+
+			elements[j] = ast.Literal{Value: element}
+		}
+
+		return ast.NewList(elements), nil
+	case reflect.Map:
+		m := ast.NewMap()
+		for _, k := range value.MapKeys() {
+			key, err := toLox(name, k.Interface())
+			if err != nil {
+				return nil, err
+			}
+
+			element, err := toLox(name, value.MapIndex(k).Interface())
+			if err != nil {
+				return nil, err
+			}
+
+			if err := m.Set(ast.Literal{Value: key}, ast.Literal{Value: element}); err != nil {
+				return nil, fmt.Errorf("%s: %v", name, err)
+			}
+		}
+
+		return m, nil
+	}
+
+	return nil, fmt.Errorf("%s: cannot convert %T to a Lox value", name, v)
+}
+
+// fromLox returns the Go value for the Lox value v: nil, bool, float64 and
+// string are returned as they are, as well as classes, instances and
+// functions, while lists become []interface{} and maps
+// map[interface{}]interface{}.
+func fromLox(v interface{}) interface{} {
+	if l, ok := v.(*ast.List); ok {
+		values := make([]interface{}, len(l.Elements))
+		for j, e := range l.Elements {
+			values[j] = fromLox(e.Value)
+		}
+
+		return values
+	}
+
+	if m, ok := v.(*ast.Map); ok {
+		values := make(map[interface{}]interface{}, m.Len())
+		for j, v := range m.Values() {
+			values[m.Keys()[j].Value] = fromLox(v.Value)
+		}
+
+		return values
+	}
+
+	return v
+}
+
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "nil"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case ast.Callable:
+		return "function"
+	case *ast.ClassInstance:
+		return "instance"
+	case *ast.List:
+		return "list"
+	case *ast.Map:
+		return "map"
+	}
+
+	return fmt.Sprintf("%T", v)
+}
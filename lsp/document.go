@@ -0,0 +1,159 @@
+//  MIT License
+//
+//  Copyright (c) 2019 Marco Pacini
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy
+//  of this software and associated documentation files (the "Software"), to deal
+//  in the Software without restriction, including without limitation the rights
+//  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//  copies of the Software, and to permit persons to whom the Software is
+//  furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all
+//  copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//  SOFTWARE.
+
+package lsp
+
+import (
+	"sort"
+	"unicode/utf8"
+
+	"github.com/marcopacini/go-lox/ast"
+)
+
+// document is an open file and the result of its analysis.
+type document struct {
+	text        string
+	tokens      []ast.Token
+	symbols     *ast.Symbols
+	diagnostics []Diagnostic
+	// offsets of the start of each line
+	lines []int
+}
+
+// newDocument scans, parses and resolves text. Errors are reported as
+// diagnostics and, when there are none, the warnings of the linter too.
+func newDocument(text string) *document {
+	d := &document{text: text, symbols: ast.NewSymbols(), lines: []int{0}, diagnostics: []Diagnostic{}}
+
+	for offset, c := range text {
+		if c == '\n' {
+			d.lines = append(d.lines, offset+1)
+		}
+	}
+
+	scanner := ast.Scanner{Text: text}
+	tokens, err := scanner.Scan()
+	d.tokens = tokens
+	d.report(err)
+
+	parser := ast.Parser{Tokens: tokens}
+	stmts, err := parser.Parse()
+	d.report(err)
+
+	resolver := ast.Resolver{Symbols: d.symbols}
+	d.report(resolver.Resolve(stmts))
+
+	if len(d.diagnostics) == 0 {
+		for _, w := range ast.Lint(stmts) {
+			d.diagnostics = append(d.diagnostics, Diagnostic{d.rangeOf(w.Span), severityWarning, "lox", w.Message})
+		}
+	}
+
+	return d
+}
+
+func (d *document) report(err error) {
+	switch e := err.(type) {
+	case ast.ErrorList:
+		for _, e := range e {
+			d.report(e)
+		}
+	case ast.Error:
+		d.diagnostics = append(d.diagnostics, Diagnostic{d.rangeOf(e.Span), severityError, "lox", e.Message})
+	}
+}
+
+// position converts a byte offset into a position.
+func (d *document) position(offset int) Position {
+	line := sort.Search(len(d.lines), func(j int) bool { return d.lines[j] > offset }) - 1
+
+	character := 0
+	for _, c := range d.text[d.lines[line]:offset] {
+		character += utf16Len(c)
+	}
+
+	return Position{line, character}
+}
+
+// offset converts a position into a byte offset, clamped to the line.
+func (d *document) offset(p Position) int {
+	if p.Line < 0 {
+		return 0
+	}
+
+	if p.Line >= len(d.lines) {
+		return len(d.text)
+	}
+
+	offset := d.lines[p.Line]
+
+	for character := 0; character < p.Character && offset < len(d.text); {
+		c, size := utf8.DecodeRuneInString(d.text[offset:])
+		if c == '\n' {
+			break
+		}
+
+		character += utf16Len(c)
+		offset += size
+	}
+
+	return offset
+}
+
+func (d *document) rangeOf(span ast.Span) Range {
+	return Range{d.position(span.Offset), d.position(span.Offset + span.Length)}
+}
+
+// utf16Len returns the number of UTF-16 code units encoding c.
+func utf16Len(c rune) int {
+	if c >= 0x10000 {
+		return 2
+	}
+
+	return 1
+}
+
+// identifier returns the identifier at p, the cursor being either on it or
+// right after it.
+func (d *document) identifier(p Position) (ast.Token, bool) {
+	offset := d.offset(p)
+
+	for _, t := range d.tokens {
+		if t.TokenType == ast.Identifier && t.Offset <= offset && offset <= t.Offset+t.Length {
+			return t, true
+		}
+	}
+
+	return ast.Token{}, false
+}
+
+// symbol returns the symbol of the identifier at p.
+func (d *document) symbol(p Position) (*ast.Symbol, ast.Token, bool) {
+	t, ok := d.identifier(p)
+	if !ok {
+		return nil, t, false
+	}
+
+	sym, ok := d.symbols.Names[t.Offset]
+
+	return sym, t, ok
+}
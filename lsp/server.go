@@ -0,0 +1,407 @@
+//  MIT License
+//
+//  Copyright (c) 2019 Marco Pacini
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy
+//  of this software and associated documentation files (the "Software"), to deal
+//  in the Software without restriction, including without limitation the rights
+//  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//  copies of the Software, and to permit persons to whom the Software is
+//  furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all
+//  copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//  SOFTWARE.
+
+// Package lsp implements a Language Server Protocol server for Lox, speaking
+// JSON-RPC over a pair of streams. Documents are synchronized in full on each
+// change.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/marcopacini/go-lox/ast"
+)
+
+type Server struct {
+	in  *bufio.Reader
+	out io.Writer
+
+	documents map[string]*document
+	// natives and constants every program can use
+	builtins map[string]interface{}
+	shutdown bool
+}
+
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{
+		in:        bufio.NewReader(in),
+		out:       out,
+		documents: make(map[string]*document),
+		builtins:  ast.NewInterpreter().Builtins.Scope,
+	}
+}
+
+// Run serves the client until it sends the exit notification, which is an
+// error if it was not preceded by a shutdown request, or closes the input.
+func (s *Server) Run() error {
+	for {
+		content, err := readMessage(s.in)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var r request
+		if err := json.Unmarshal(content, &r); err != nil {
+			if err := s.fail(nil, codeParseError, err.Error()); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if r.Method == "exit" {
+			if !s.shutdown {
+				return fmt.Errorf("exit before shutdown")
+			}
+
+			return nil
+		}
+
+		if err := s.handle(r); err != nil {
+			return err
+		}
+	}
+}
+
+// handle answers r, if it is a request.
+func (s *Server) handle(r request) error {
+	result, err := s.dispatch(r)
+
+	if r.ID == nil {
+		return nil
+	}
+
+	switch e := err.(type) {
+	case nil:
+		return writeMessage(s.out, response{"2.0", r.ID, result})
+	case responseError:
+		return s.fail(r.ID, e.Code, e.Message)
+	default:
+		return s.fail(r.ID, codeInvalidParams, err.Error())
+	}
+}
+
+func (s *Server) fail(id *json.RawMessage, code int, message string) error {
+	return writeMessage(s.out, errorResponse{"2.0", id, responseError{code, message}})
+}
+
+func (e responseError) Error() string {
+	return e.Message
+}
+
+func (s *Server) dispatch(r request) (interface{}, error) {
+	switch r.Method {
+	case "initialize":
+		return s.initialize(), nil
+
+	case "initialized":
+		return nil, nil
+
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+
+	case "textDocument/didOpen":
+		var params didOpenParams
+		if err := json.Unmarshal(r.Params, &params); err != nil {
+			return nil, err
+		}
+
+		return nil, s.update(params.TextDocument.URI, params.TextDocument.Text)
+
+	case "textDocument/didChange":
+		var params didChangeParams
+		if err := json.Unmarshal(r.Params, &params); err != nil {
+			return nil, err
+		}
+
+		if n := len(params.ContentChanges); n > 0 {
+			return nil, s.update(params.TextDocument.URI, params.ContentChanges[n-1].Text)
+		}
+
+		return nil, nil
+
+	case "textDocument/didClose":
+		var params didCloseParams
+		if err := json.Unmarshal(r.Params, &params); err != nil {
+			return nil, err
+		}
+
+		delete(s.documents, params.TextDocument.URI)
+
+		return nil, s.publish(params.TextDocument.URI, []Diagnostic{})
+
+	case "textDocument/definition":
+		var params textDocumentPositionParams
+		if err := json.Unmarshal(r.Params, &params); err != nil {
+			return nil, err
+		}
+
+		return s.definition(params)
+
+	case "textDocument/references":
+		var params referenceParams
+		if err := json.Unmarshal(r.Params, &params); err != nil {
+			return nil, err
+		}
+
+		return s.references(params)
+
+	case "textDocument/hover":
+		var params textDocumentPositionParams
+		if err := json.Unmarshal(r.Params, &params); err != nil {
+			return nil, err
+		}
+
+		return s.hover(params)
+
+	case "textDocument/documentSymbol":
+		var params documentSymbolParams
+		if err := json.Unmarshal(r.Params, &params); err != nil {
+			return nil, err
+		}
+
+		return s.documentSymbols(params)
+
+	case "textDocument/completion":
+		var params textDocumentPositionParams
+		if err := json.Unmarshal(r.Params, &params); err != nil {
+			return nil, err
+		}
+
+		return s.completion(params)
+	}
+
+	return nil, responseError{codeMethodNotFound, fmt.Sprintf("method not found: %s", r.Method)}
+}
+
+func (s *Server) initialize() interface{} {
+	return map[string]interface{}{
+		"capabilities": map[string]interface{}{
+			"textDocumentSync":       1, // full
+			"definitionProvider":     true,
+			"referencesProvider":     true,
+			"hoverProvider":          true,
+			"documentSymbolProvider": true,
+			"completionProvider":     map[string]interface{}{},
+		},
+		"serverInfo": map[string]string{"name": "lox"},
+	}
+}
+
+func (s *Server) document(uri string) (*document, error) {
+	d, ok := s.documents[uri]
+	if !ok {
+		return nil, fmt.Errorf("unknown document %s", uri)
+	}
+
+	return d, nil
+}
+
+// update analyzes the new text of the document and publishes its
+// diagnostics.
+func (s *Server) update(uri string, text string) error {
+	d := newDocument(text)
+	s.documents[uri] = d
+
+	return s.publish(uri, d.diagnostics)
+}
+
+func (s *Server) publish(uri string, diagnostics []Diagnostic) error {
+	params := publishDiagnosticsParams{uri, diagnostics}
+	return writeMessage(s.out, notification{"2.0", "textDocument/publishDiagnostics", params})
+}
+
+func (s *Server) definition(params textDocumentPositionParams) (interface{}, error) {
+	d, err := s.document(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	sym, _, ok := d.symbol(params.Position)
+	if !ok {
+		return nil, nil
+	}
+
+	return Location{params.TextDocument.URI, d.rangeOf(sym.Declaration)}, nil
+}
+
+func (s *Server) references(params referenceParams) (interface{}, error) {
+	d, err := s.document(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	locations := []Location{}
+
+	sym, _, ok := d.symbol(params.Position)
+	if !ok {
+		return locations, nil
+	}
+
+	if params.Context.IncludeDeclaration {
+		locations = append(locations, Location{params.TextDocument.URI, d.rangeOf(sym.Declaration)})
+	}
+
+	for _, span := range sym.References {
+		locations = append(locations, Location{params.TextDocument.URI, d.rangeOf(span)})
+	}
+
+	return locations, nil
+}
+
+func (s *Server) hover(params textDocumentPositionParams) (interface{}, error) {
+	d, err := s.document(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	t, ok := d.identifier(params.Position)
+	if !ok {
+		return nil, nil
+	}
+
+	var signature string
+
+	if sym, ok := d.symbols.Names[t.Offset]; ok {
+		signature = sym.Signature()
+	} else if v, ok := s.builtins[t.Lexeme]; ok {
+		signature = builtinSignature(t.Lexeme, v)
+	} else {
+		return nil, nil
+	}
+
+	r := d.rangeOf(t.Span())
+
+	return Hover{MarkupContent{"markdown", "```lox\n" + signature + "\n```"}, &r}, nil
+}
+
+// builtinSignature describes a native function or constant.
+func builtinSignature(name string, value interface{}) string {
+	if l, ok := value.(ast.Literal); ok {
+		if f, ok := l.Value.(ast.Callable); ok {
+			if f.Arity() < 0 {
+				return fmt.Sprintf("native function %s, any number of arguments", name)
+			}
+
+			if f.Arity() == 1 {
+				return fmt.Sprintf("native function %s, 1 argument", name)
+			}
+
+			return fmt.Sprintf("native function %s, %d arguments", name, f.Arity())
+		}
+
+		return fmt.Sprintf("constant %s = %v", name, l)
+	}
+
+	return name
+}
+
+// documentSymbols lists the global functions and classes, with their
+// methods.
+func (s *Server) documentSymbols(params documentSymbolParams) (interface{}, error) {
+	d, err := s.document(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	symbols := []DocumentSymbol{}
+
+	for _, sym := range d.symbols.Global.Symbols {
+		switch sym.Kind {
+		case ast.FunctionSymbol:
+			symbols = append(symbols, d.documentSymbol(sym, symbolFunction))
+		case ast.ClassSymbol:
+			class := d.documentSymbol(sym, symbolClass)
+			for _, m := range sym.Methods {
+				class.Children = append(class.Children, d.documentSymbol(m, symbolMethod))
+			}
+
+			symbols = append(symbols, class)
+		}
+	}
+
+	return symbols, nil
+}
+
+func (d *document) documentSymbol(sym *ast.Symbol, kind int) DocumentSymbol {
+	return DocumentSymbol{sym.Name, sym.Signature(), kind, d.rangeOf(sym.Span), d.rangeOf(sym.Declaration), nil}
+}
+
+// completion lists the names visible at the position: the declared ones,
+// the builtins and the keywords.
+func (s *Server) completion(params textDocumentPositionParams) (interface{}, error) {
+	d, err := s.document(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	items := []CompletionItem{}
+	seen := make(map[string]bool)
+
+	for _, sym := range d.symbols.Visible(d.offset(params.Position)) {
+		kind := completionVariable
+		switch sym.Kind {
+		case ast.FunctionSymbol:
+			kind = completionFunction
+		case ast.ClassSymbol:
+			kind = completionClass
+		case ast.ModuleSymbol:
+			kind = completionModule
+		}
+
+		seen[sym.Name] = true
+		items = append(items, CompletionItem{sym.Name, kind, sym.Signature()})
+	}
+
+	var builtins []string
+	for name := range s.builtins {
+		if !seen[name] {
+			builtins = append(builtins, name)
+		}
+	}
+
+	sort.Strings(builtins)
+
+	for _, name := range builtins {
+		kind := completionConstant
+		if l, ok := s.builtins[name].(ast.Literal); ok {
+			if _, ok := l.Value.(ast.Callable); ok {
+				kind = completionFunction
+			}
+		}
+
+		items = append(items, CompletionItem{name, kind, builtinSignature(name, s.builtins[name])})
+	}
+
+	for _, keyword := range ast.Keywords() {
+		items = append(items, CompletionItem{Label: keyword, Kind: completionKeyword})
+	}
+
+	return items, nil
+}
@@ -0,0 +1,105 @@
+//  MIT License
+//
+//  Copyright (c) 2019 Marco Pacini
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy
+//  of this software and associated documentation files (the "Software"), to deal
+//  in the Software without restriction, including without limitation the rights
+//  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//  copies of the Software, and to permit persons to whom the Software is
+//  furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all
+//  copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//  SOFTWARE.
+
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const source = `var g = 1;
+fun add(a, b) {
+  var unused;
+  return a + b;
+}
+class P { init(x) { this.x = x; } }
+print add(g, 2);
+`
+
+// TestServer plays a session as a client would and checks the answers.
+func TestServer(t *testing.T) {
+	position := func(line, character int) string {
+		return fmt.Sprintf(`"textDocument": {"uri": "file:///a.lox"}, "position": {"line": %d, "character": %d}`, line, character)
+	}
+
+	text, _ := json.Marshal(source)
+
+	requests := []string{
+		`{"jsonrpc": "2.0", "id": 1, "method": "initialize", "params": {}}`,
+		`{"jsonrpc": "2.0", "method": "textDocument/didOpen", "params": {"textDocument": {"uri": "file:///a.lox", "version": 1, "text": ` + string(text) + `}}}`,
+		`{"jsonrpc": "2.0", "id": 2, "method": "textDocument/definition", "params": {` + position(6, 7) + `}}`,
+		`{"jsonrpc": "2.0", "id": 3, "method": "textDocument/references", "params": {` + position(0, 4) + `, "context": {"includeDeclaration": true}}}`,
+		`{"jsonrpc": "2.0", "id": 4, "method": "textDocument/hover", "params": {` + position(5, 7) + `}}`,
+		`{"jsonrpc": "2.0", "id": 5, "method": "textDocument/documentSymbol", "params": {"textDocument": {"uri": "file:///a.lox"}}}`,
+		`{"jsonrpc": "2.0", "id": 6, "method": "textDocument/completion", "params": {` + position(3, 2) + `}}`,
+		`{"jsonrpc": "2.0", "method": "textDocument/didChange", "params": {"textDocument": {"uri": "file:///a.lox"}, "contentChanges": [{"text": "print (;"}]}}`,
+		`{"jsonrpc": "2.0", "id": 7, "method": "shutdown"}`,
+		`{"jsonrpc": "2.0", "method": "exit"}`,
+	}
+
+	var in, out bytes.Buffer
+	for _, r := range requests {
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(r), r)
+	}
+
+	if err := NewServer(&in, &out).Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		`"definitionProvider":true`,
+		`"message":"unused variable unused","range":{"end":{"character":12,"line":2},"start":{"character":6,"line":2}}`,
+		`"result":{"range":{"end":{"character":7,"line":1},"start":{"character":4,"line":1}},"uri":"file:///a.lox"}`,
+		`"result":[{"range":{"end":{"character":5,"line":0},"start":{"character":4,"line":0}},"uri":"file:///a.lox"},{"range":{"end":{"character":11,"line":6},"start":{"character":10,"line":6}},"uri":"file:///a.lox"}]`,
+		`"result":{"contents":{"kind":"markdown","value":"` + "```lox\\nclass P(x)\\n```" + `"}`,
+		`"result":[{"detail":"function add(a, b)"`,
+		`"result":[{"detail":"variable unused","kind":6,"label":"unused"},{"detail":"parameter b","kind":6,"label":"b"}`,
+		`"message":"unknown token ';'"`,
+		`"result":null`,
+	}
+
+	r := bufio.NewReader(&out)
+
+	for _, w := range want {
+		content, err := readMessage(r)
+		if err != nil {
+			t.Fatalf("want %s, got %v", w, err)
+		}
+
+		// normalize the order of the fields
+		var message interface{}
+		if err := json.Unmarshal(content, &message); err != nil {
+			t.Fatal(err)
+		}
+
+		normalized, _ := json.Marshal(message)
+
+		if !strings.Contains(string(normalized), w) {
+			t.Errorf("want %s in %s", w, normalized)
+		}
+	}
+}
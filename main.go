@@ -1,19 +1,71 @@
 package main
 
 import (
-	"bufio"
 	"flag"
 	"fmt"
 	"github.com/marcopacini/go-lox/ast"
+	"github.com/marcopacini/go-lox/debug"
+	"github.com/marcopacini/go-lox/lsp"
+	"github.com/marcopacini/go-lox/vm"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 )
 
+var useVM = flag.Bool("vm", false, "run scripts on the bytecode virtual machine")
+
+// runner executes parsed programs, keeping global state between runs.
+type runner interface {
+	Run(stmts []ast.Stmt) error
+}
+
+type machine struct {
+	*vm.VM
+}
+
+func (m machine) Run(stmts []ast.Stmt) error {
+	f, err := vm.Compile(stmts)
+	if err != nil {
+		return err
+	}
+
+	return m.Interpret(f)
+}
+
+func newRunner() runner {
+	if *useVM {
+		return machine{vm.New()}
+	}
+
+	return ast.NewInterpreter()
+}
+
 func main() {
 	flag.Parse()
 
+	switch flag.Arg(0) {
+	case "fmt":
+		os.Exit(runFormat(flag.Args()[1:]))
+	case "lint":
+		os.Exit(runLint(flag.Args()[1:]))
+	case "debug":
+		os.Exit(runDebug(flag.Args()[1:]))
+	case "dap":
+		if err := debug.NewAdapter(os.Stdin, os.Stdout).Run(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	case "lsp":
+		if err := lsp.NewServer(os.Stdin, os.Stdout).Run(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
 	if len(flag.Args()) > 1 {
-		println("usage: lox [script]")
+		fmt.Fprintln(os.Stderr, "usage: lox [-vm] [script | -]\n       lox fmt [-w] [-d] [path ...]\n       lox lint path ...\n       lox lsp\n       lox debug script\n       lox dap")
 		os.Exit(64)
 	}
 
@@ -24,67 +76,145 @@ func main() {
 	}
 }
 
+// Exit statuses, as defined by sysexits.h.
+const (
+	exitData       = 65
+	exitNoInput    = 66
+	exitSoftware   = 70
+	exitCantCreate = 73
+)
+
+// runFile runs the script at path, or the one read from standard input when
+// path is "-", and exits with a non-zero status if it fails.
 func runFile(path string) {
-	b, err := ioutil.ReadFile(path)
+	var b []byte
+	var err error
+
+	if path == "-" {
+		b, err = ioutil.ReadAll(os.Stdin)
+		path = ""
+	} else {
+		b, err = ioutil.ReadFile(path)
+	}
+
 	if err != nil {
-		panic(err)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitNoInput)
 	}
 
-	if err := run(string(b)); err != nil {
-		fmt.Println(err)
+	r := newRunner()
+	setPath(r, path)
+
+	if err := run(r, string(b), false); err != nil {
+		report(string(b), err)
+		os.Exit(exitStatus(err))
 	}
 }
 
-func runPrompt() {
-	reader := bufio.NewReader(os.Stdin)
+// exitStatus tells runtime errors apart from errors found before the program
+// starts running.
+func exitStatus(err error) int {
+	switch err.(type) {
+	case ast.RuntimeError, vm.RuntimeError:
+		return exitSoftware
+	default:
+		return exitData
+	}
+}
 
-	for {
-		fmt.Print("> ")
+// forEachFile calls f on each file named in paths and on the .lox files in
+// the directories named in paths. It returns the last non-zero status
+// returned by f.
+func forEachFile(paths []string, f func(path string, info os.FileInfo) int) int {
+	status := 0
 
-		if b, err := reader.ReadString('\n'); err == nil {
-			if err := run(string(b)); err != nil {
-				fmt.Println(err)
+	for _, root := range paths {
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			// files given by name are used whatever their extension
+			if info.IsDir() || (path != root && filepath.Ext(path) != ".lox") {
+				return nil
 			}
+
+			if s := f(path, info); s != 0 {
+				status = s
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			status = exitNoInput
 		}
 	}
+
+	return status
 }
 
-func run(source string) error {
-	s := ast.Scanner{source}
+// setPath sets the file imports are resolved against, where supported. An
+// empty path stands for the working directory.
+func setPath(r runner, path string) {
+	if i, ok := r.(*ast.Interpreter); ok {
+		i.Path = ""
 
-	tokens, err := s.Scan()
-	if err != nil {
-		return err
+		if path != "" {
+			i.Path, _ = filepath.Abs(path)
+		}
 	}
+}
 
-	//	for _, token := range tokens {
-	//		fmt.Println(token)
-	//	}
+func report(source string, err error) {
+	switch e := err.(type) {
+	case ast.RuntimeError:
+		// errors in imported modules are shown in their own source
+		if e.File != "" {
+			b, _ := ioutil.ReadFile(e.File)
+			source = string(b)
+		}
 
-	p := ast.Parser{Tokens: tokens}
+		fmt.Fprintln(os.Stderr, e.Render(source))
+	case ast.ModuleError:
+		b, _ := ioutil.ReadFile(e.File)
+		fmt.Fprintln(os.Stderr, e.Render(string(b)))
+	case vm.RuntimeError:
+		fmt.Fprintln(os.Stderr, e.Render(source))
+	case ast.ErrorList:
+		fmt.Fprintln(os.Stderr, e.Render(source))
+	case ast.Error:
+		fmt.Fprintln(os.Stderr, e.Render(source))
+	default:
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
 
-	stmts, err := p.Parse()
+// run scans, parses and runs source. When echo is set, the value of each
+// top-level expression statement is printed, as a REPL does.
+func run(r runner, source string, echo bool) error {
+	s := ast.Scanner{Text: source}
+
+	tokens, err := s.Scan()
 	if err != nil {
 		return err
 	}
 
-	i := ast.Interpreter{}
+	p := ast.Parser{Tokens: tokens}
 
-	if err = i.Run(stmts); err != nil {
+	stmts, err := p.Parse()
+	if err != nil {
 		return err
 	}
 
-	/*
-		if err := parser.Resolve(ast); err != nil {
-			return err
-		}
-
-		for _, stmt := range ast {
-			if _, err := stmt.Evaluate(env); err != nil {
-				return err
+	if echo {
+		for j, stmt := range stmts {
+			if e, ok := stmt.(ast.ExprStmt); ok {
+				stmts[j] = ast.PrintStmt{Expr: e.Expr, Span: e.Span}
 			}
 		}
-	*/
+	}
 
-	return nil
+	return r.Run(stmts)
 }
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"github.com/marcopacini/go-lox/ast"
+	"github.com/marcopacini/go-lox/readline"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const help = `:load <file>  run a file in the current session
+:env          list the global variables
+:reset        discard all definitions
+:help         show this message
+:quit         leave the prompt`
+
+func runPrompt() {
+	r := newRunner()
+
+	// input piped from a file is not kept in the history
+	history := ""
+	if home, err := os.UserHomeDir(); err == nil && readline.IsTerminal(os.Stdin) {
+		history = filepath.Join(home, ".lox_history")
+	}
+
+	reader := readline.New(os.Stdin, os.Stdout, history)
+	defer reader.Close()
+
+	prompt := "> "
+	if !reader.Interactive() {
+		prompt = ""
+	}
+
+	for {
+		source, err := readInput(reader, prompt)
+		if err == readline.ErrInterrupt {
+			continue
+		}
+		if err != nil {
+			if err != io.EOF {
+				fmt.Fprintln(os.Stderr, err)
+			}
+			return
+		}
+
+		reader.AddHistory(source)
+
+		line := strings.TrimSpace(source)
+		if !strings.HasPrefix(line, ":") {
+			if err := run(r, source, true); err != nil {
+				report(source, err)
+			}
+			continue
+		}
+
+		fields := strings.Fields(line)
+
+		switch fields[0] {
+		case ":load":
+			if len(fields) != 2 {
+				fmt.Fprintln(os.Stderr, "usage: :load <file>")
+				continue
+			}
+
+			b, err := ioutil.ReadFile(fields[1])
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				continue
+			}
+
+			setPath(r, fields[1])
+
+			if err := run(r, string(b), false); err != nil {
+				report(string(b), err)
+			}
+
+			setPath(r, "")
+		case ":env":
+			printGlobals(r)
+		case ":reset":
+			r = newRunner()
+		case ":help":
+			fmt.Println(help)
+		case ":quit":
+			return
+		default:
+			fmt.Fprintf(os.Stderr, "unknown command %s, type :help for a list\n", fields[0])
+		}
+	}
+}
+
+// readInput reads lines until they form a complete chunk of source, showing a
+// continuation prompt while braces or parentheses are left open.
+func readInput(reader *readline.Reader, prompt string) (string, error) {
+	var lines []string
+
+	for {
+		line, err := reader.ReadLine(prompt)
+		if err == io.EOF && len(lines) > 0 {
+			return strings.Join(lines, "\n"), nil
+		}
+		if err != nil {
+			return "", err
+		}
+
+		lines = append(lines, line)
+		source := strings.Join(lines, "\n")
+
+		if strings.HasPrefix(strings.TrimSpace(source), ":") || complete(source) {
+			return source, nil
+		}
+
+		if reader.Interactive() {
+			prompt = "... "
+		}
+	}
+}
+
+// complete reports whether source has no unterminated string and no unclosed
+// parenthesis, brace or bracket.
+func complete(source string) bool {
+	s := ast.Scanner{Text: source}
+
+	tokens, err := s.Scan()
+	if errs, ok := err.(ast.ErrorList); ok {
+		for _, e := range errs {
+			if e.Message == "unterminated string" {
+				return false
+			}
+		}
+	}
+
+	depth := 0
+	for _, t := range tokens {
+		switch t.TokenType {
+		case ast.LeftParenthesis, ast.LeftBrace, ast.LeftBracket:
+			depth++
+		case ast.RightParenthesis, ast.RightBrace, ast.RightBracket:
+			depth--
+		}
+	}
+
+	return depth <= 0
+}
+
+func printGlobals(r runner) {
+	globals := make(map[string]string)
+
+	switch r := r.(type) {
+	case *ast.Interpreter:
+		for name, value := range r.Globals.Scope {
+			globals[name] = fmt.Sprint(value)
+		}
+	case machine:
+		for name, value := range r.Globals() {
+			globals[name] = ast.Literal{Value: value}.String()
+		}
+	}
+
+	names := make([]string, 0, len(globals))
+	for name := range globals {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Printf("%s = %s\n", name, globals[name])
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"github.com/marcopacini/go-lox/readline"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestComplete(t *testing.T) {
+	table := []struct {
+		in  string
+		out bool
+	}{
+		{"print 1;", true},
+		{"", true},
+		{"fun f() {", false},
+		{"fun f() {\n  return 1;\n}", true},
+		{"print [1, 2", false},
+		{"print f(1,\n2);", true},
+		{"print \"abc", false},
+		{"print \"a\nb\";", true},
+		{"}", true},
+		{"print 1; // {", true},
+	}
+
+	for _, test := range table {
+		t.Run(test.in, func(t *testing.T) {
+			if out := complete(test.in); out != test.out {
+				t.Errorf("want %v, got %v", test.out, out)
+			}
+		})
+	}
+}
+
+func TestReadInput(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w.WriteString("fun f() {\n  return 1;\n}\nprint f();\n:load x.lox\nclass A {")
+	w.Close()
+
+	reader := readline.New(r, ioutil.Discard, "")
+
+	for _, want := range []string{"fun f() {\n  return 1;\n}", "print f();", ":load x.lox", "class A {"} {
+		source, err := readInput(reader, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if source != want {
+			t.Errorf("want %q, got %q", want, source)
+		}
+	}
+
+	if _, err := readInput(reader, ""); err != io.EOF {
+		t.Errorf("want io.EOF, got %v", err)
+	}
+}
@@ -0,0 +1,359 @@
+//  MIT License
+//
+//  Copyright (c) 2019 Marco Pacini
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy
+//  of this software and associated documentation files (the "Software"), to deal
+//  in the Software without restriction, including without limitation the rights
+//  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//  copies of the Software, and to permit persons to whom the Software is
+//  furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all
+//  copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//  SOFTWARE.
+
+// Package readline reads lines from a terminal with basic editing: cursor
+// movement, deletion, and a history browsed with the arrow keys and saved in
+// a file. When the input is not a terminal, lines are read as they are.
+package readline
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrInterrupt is returned by ReadLine when the user presses Ctrl-C.
+var ErrInterrupt = errors.New("interrupt")
+
+// HistorySize is the number of lines kept in the history file.
+const HistorySize = 1000
+
+const (
+	keyCtrlA     = 1
+	keyCtrlB     = 2
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyCtrlF     = 6
+	keyCtrlK     = 11
+	keyCtrlL     = 12
+	keyEnter     = 13
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyCtrlU     = 21
+	keyEscape    = 27
+	keyBackspace = 127
+)
+
+type Reader struct {
+	in          *os.File
+	out         io.Writer
+	terminal    bool
+	lines       *bufio.Reader
+	history     []string
+	historyPath string
+}
+
+// New returns a Reader for in, echoing to out. The history is loaded from
+// historyPath, if not empty, and saved there by Close.
+func New(in *os.File, out io.Writer, historyPath string) *Reader {
+	r := &Reader{
+		in:          in,
+		out:         out,
+		terminal:    IsTerminal(in),
+		lines:       bufio.NewReader(in),
+		historyPath: historyPath,
+	}
+
+	if historyPath != "" {
+		if b, err := ioutil.ReadFile(historyPath); err == nil {
+			for _, line := range strings.Split(string(b), "\n") {
+				if line != "" {
+					r.history = append(r.history, unescaper.Replace(line))
+				}
+			}
+		}
+	}
+
+	return r
+}
+
+// IsTerminal reports whether f is a terminal.
+func IsTerminal(f *os.File) bool {
+	return isTerminal(f.Fd())
+}
+
+// Interactive reports whether the input is a terminal.
+func (r *Reader) Interactive() bool {
+	return r.terminal
+}
+
+// AddHistory appends line to the history, unless it is empty or repeats the
+// last entry. A line can span several lines of input, as a function typed at
+// a continuation prompt: it is recalled as a whole.
+func (r *Reader) AddHistory(line string) {
+	line = strings.TrimRight(line, "\r\n")
+
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+
+	if n := len(r.history); n > 0 && r.history[n-1] == line {
+		return
+	}
+
+	r.history = append(r.history, line)
+}
+
+// Close saves the history.
+func (r *Reader) Close() error {
+	if r.historyPath == "" {
+		return nil
+	}
+
+	history := r.history
+	if len(history) > HistorySize {
+		history = history[len(history)-HistorySize:]
+	}
+
+	lines := make([]string, len(history))
+	for i, line := range history {
+		lines[i] = escaper.Replace(line)
+	}
+
+	return ioutil.WriteFile(r.historyPath, []byte(strings.Join(lines, "\n")+"\n"), 0600)
+}
+
+// the history file has an entry per line, newlines in the entries are
+// written as \n and backslashes as \\
+var (
+	escaper   = strings.NewReplacer("\\", "\\\\", "\n", "\\n")
+	unescaper = strings.NewReplacer("\\\\", "\\", "\\n", "\n")
+)
+
+// ReadLine shows prompt and returns the line entered by the user, without the
+// trailing newline. At the end of the input it returns io.EOF.
+func (r *Reader) ReadLine(prompt string) (string, error) {
+	if !r.terminal {
+		line, err := r.lines.ReadString('\n')
+		if err == io.EOF && line != "" {
+			err = nil
+		}
+
+		return strings.TrimRight(line, "\r\n"), err
+	}
+
+	restore, err := makeRaw(r.in.Fd())
+	if err != nil {
+		return "", err
+	}
+	defer restore()
+
+	return r.edit(prompt)
+}
+
+func (r *Reader) readRune() (rune, error) {
+	var b [utf8.UTFMax]byte
+
+	for n := 0; n < len(b); n++ {
+		if _, err := r.in.Read(b[n : n+1]); err != nil {
+			return 0, err
+		}
+
+		if utf8.FullRune(b[:n+1]) {
+			c, _ := utf8.DecodeRune(b[:n+1])
+			return c, nil
+		}
+	}
+
+	return utf8.RuneError, nil
+}
+
+func (r *Reader) edit(prompt string) (string, error) {
+	var line []rune
+	pos := 0
+
+	// the entries of the history, followed by the line being edited
+	entries := append(append([]string{}, r.history...), "")
+	index := len(entries) - 1
+
+	// the newlines of an entry recalled from the history are shown as ⏎
+	refresh := func() {
+		fmt.Fprintf(r.out, "\r%s%s\x1b[K", prompt, strings.Replace(string(line), "\n", "⏎", -1))
+
+		if back := len(line) - pos; back > 0 {
+			fmt.Fprintf(r.out, "\x1b[%dD", back)
+		}
+	}
+
+	browse := func(to int) {
+		if to < 0 || to >= len(entries) {
+			return
+		}
+
+		entries[index] = string(line)
+		index = to
+		line = []rune(entries[index])
+		pos = len(line)
+	}
+
+	refresh()
+
+	for {
+		c, err := r.readRune()
+		if err != nil {
+			return "", err
+		}
+
+		switch c {
+		case keyEnter, '\n':
+			fmt.Fprint(r.out, "\r\n")
+			return string(line), nil
+
+		case keyCtrlC:
+			fmt.Fprint(r.out, "^C\r\n")
+			return "", ErrInterrupt
+
+		case keyCtrlD:
+			if len(line) == 0 {
+				fmt.Fprint(r.out, "\r\n")
+				return "", io.EOF
+			}
+
+			if pos < len(line) {
+				line = append(line[:pos], line[pos+1:]...)
+			}
+
+		case keyBackspace, '\b':
+			if pos > 0 {
+				line = append(line[:pos-1], line[pos:]...)
+				pos--
+			}
+
+		case keyCtrlA:
+			pos = 0
+
+		case keyCtrlE:
+			pos = len(line)
+
+		case keyCtrlB:
+			if pos > 0 {
+				pos--
+			}
+
+		case keyCtrlF:
+			if pos < len(line) {
+				pos++
+			}
+
+		case keyCtrlK:
+			line = line[:pos]
+
+		case keyCtrlU:
+			line = line[pos:]
+			pos = 0
+
+		case keyCtrlL:
+			fmt.Fprint(r.out, "\x1b[H\x1b[2J")
+
+		case keyCtrlP:
+			browse(index - 1)
+
+		case keyCtrlN:
+			browse(index + 1)
+
+		case keyEscape:
+			key, err := r.escape()
+			if err != nil {
+				return "", err
+			}
+
+			switch key {
+			case 'A':
+				browse(index - 1)
+			case 'B':
+				browse(index + 1)
+			case 'C':
+				if pos < len(line) {
+					pos++
+				}
+			case 'D':
+				if pos > 0 {
+					pos--
+				}
+			case 'H':
+				pos = 0
+			case 'F':
+				pos = len(line)
+			case '~':
+				if pos < len(line) {
+					line = append(line[:pos], line[pos+1:]...)
+				}
+			}
+
+		case '\t':
+			line = append(line[:pos], append([]rune("  "), line[pos:]...)...)
+			pos += 2
+
+		default:
+			if c >= ' ' {
+				line = append(line[:pos], append([]rune{c}, line[pos:]...)...)
+				pos++
+			}
+		}
+
+		refresh()
+	}
+}
+
+// escape reads the rest of an escape sequence and returns the letter of the
+// arrow, home or end key pressed, or '~' for the delete key.
+func (r *Reader) escape() (rune, error) {
+	c, err := r.readRune()
+	if err != nil || (c != '[' && c != 'O') {
+		return 0, err
+	}
+
+	c, err = r.readRune()
+	if err != nil {
+		return 0, err
+	}
+
+	if c >= '0' && c <= '9' {
+		n := c
+
+		// sequences like "ESC [ 3 ~": only delete is handled
+		for c >= '0' && c <= '9' {
+			if c, err = r.readRune(); err != nil {
+				return 0, err
+			}
+		}
+
+		switch {
+		case c == '~' && n == '3':
+			return '~', nil
+		case c == '~' && (n == '1' || n == '7'):
+			return 'H', nil
+		case c == '~' && (n == '4' || n == '8'):
+			return 'F', nil
+		}
+
+		return 0, nil
+	}
+
+	return c, nil
+}
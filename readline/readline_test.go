@@ -0,0 +1,149 @@
+//  MIT License
+//
+//  Copyright (c) 2019 Marco Pacini
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy
+//  of this software and associated documentation files (the "Software"), to deal
+//  in the Software without restriction, including without limitation the rights
+//  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//  copies of the Software, and to permit persons to whom the Software is
+//  furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all
+//  copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//  SOFTWARE.
+
+package readline
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// input returns a file from which s can be read.
+func input(t *testing.T, s string) *os.File {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := w.WriteString(s); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	return r
+}
+
+func TestReader_ReadLine(t *testing.T) {
+	r := New(input(t, "print 1;\r\nprint 2;"), ioutil.Discard, "")
+
+	for _, want := range []string{"print 1;", "print 2;"} {
+		line, err := r.ReadLine("> ")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if line != want {
+			t.Errorf("want %q, got %q", want, line)
+		}
+	}
+
+	if _, err := r.ReadLine("> "); err != io.EOF {
+		t.Errorf("want io.EOF, got %v", err)
+	}
+}
+
+func TestReader_History(t *testing.T) {
+	dir, err := ioutil.TempDir("", "readline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "history")
+
+	r := New(input(t, ""), ioutil.Discard, path)
+	for _, line := range []string{"print 1;", "print 1;", "  ", "fun f() {\n  print \"a\\b\";\n}\n"} {
+		r.AddHistory(line)
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "print 1;\nfun f() {\\n  print \"a\\\\b\";\\n}\n"; string(b) != want {
+		t.Errorf("want file %q, got %q", want, b)
+	}
+
+	want := []string{"print 1;", "fun f() {\n  print \"a\\b\";\n}"}
+	if history := New(input(t, ""), ioutil.Discard, path).history; !reflect.DeepEqual(history, want) {
+		t.Errorf("want history %q, got %q", want, history)
+	}
+}
+
+func TestReader_Edit(t *testing.T) {
+	table := []struct {
+		keys string
+		line string
+		err  error
+	}{
+		{"abc\r", "abc", nil},
+		{"bc\x01a\x05d\r", "abcd", nil},
+		{"abd\x1b[Dc\x1b[C\x7fe\r", "abce", nil},
+		{"abc\x1b[D\x1b[D\x1b[3~\r", "ac", nil},
+		{"abc\x02\x02\x0b\r", "a", nil},
+		{"abc\x02\x15\r", "c", nil},
+		{"\x1b[A\r", "fun f() {\n}", nil},
+		{"\x1b[A\x1b[A\r", "print 1;", nil},
+		{"\x10\x10\x0e\r", "fun f() {\n}", nil},
+		{"x\x1b[A\x1b[B\r", "x", nil},
+		{"abc\x03", "", ErrInterrupt},
+		{"\x04", "", io.EOF},
+	}
+
+	for _, test := range table {
+		t.Run(test.keys, func(t *testing.T) {
+			r := New(input(t, test.keys), ioutil.Discard, "")
+			r.history = []string{"print 1;", "fun f() {\n}"}
+
+			line, err := r.edit("> ")
+			if line != test.line || err != test.err {
+				t.Errorf("want %q, %v, got %q, %v", test.line, test.err, line, err)
+			}
+		})
+	}
+}
+
+// TestReader_EditMultiline shows the newlines of a history entry on one line.
+func TestReader_EditMultiline(t *testing.T) {
+	var out bytes.Buffer
+
+	r := New(input(t, "\x1b[A\r"), &out, "")
+	r.history = []string{"fun f() {\n}"}
+
+	if _, err := r.edit("> "); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "\r> fun f() {⏎}\x1b[K"; !bytes.Contains(out.Bytes(), []byte(want)) {
+		t.Errorf("want %q in %q", want, out.String())
+	}
+}
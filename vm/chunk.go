@@ -0,0 +1,129 @@
+//  MIT License
+//
+//  Copyright (c) 2019 Marco Pacini
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy
+//  of this software and associated documentation files (the "Software"), to deal
+//  in the Software without restriction, including without limitation the rights
+//  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//  copies of the Software, and to permit persons to whom the Software is
+//  furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all
+//  copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//  SOFTWARE.
+
+package vm
+
+import (
+	"github.com/marcopacini/go-lox/ast"
+	"sort"
+)
+
+type OpCode byte
+
+const (
+	OpConstant OpCode = iota
+	OpNil
+	OpTrue
+	OpFalse
+	OpPop
+	OpGetLocal
+	OpSetLocal
+	OpGetGlobal
+	OpDefineGlobal
+	OpSetGlobal
+	OpGetUpvalue
+	OpSetUpvalue
+	OpGetProperty
+	OpSetProperty
+	OpGetSuper
+	OpEqual
+	OpGreater
+	OpLess
+	OpAdd
+	OpSubtract
+	OpMultiply
+	OpDivide
+	OpModulo
+	OpPower
+	OpNot
+	OpNegate
+	OpPrint
+	OpJump
+	OpJumpIfFalse
+	OpLoop
+	OpCall
+	OpInvoke
+	OpSuperInvoke
+	OpClosure
+	OpCloseUpvalue
+	OpReturn
+	OpClass
+	OpInherit
+	OpMethod
+)
+
+type spanStart struct {
+	offset int
+	span   ast.Span
+}
+
+// Chunk is a sequence of bytecode together with its constants pool. Source
+// spans are run-length encoded: a new entry is recorded only when the span of
+// the emitted byte differs from the previous one.
+type Chunk struct {
+	Code      []byte
+	Constants []Value
+	spans     []spanStart
+	indexes   map[Value]int
+}
+
+func (c *Chunk) Write(b byte, span ast.Span) {
+	if n := len(c.spans); n == 0 || c.spans[n-1].span != span {
+		c.spans = append(c.spans, spanStart{len(c.Code), span})
+	}
+
+	c.Code = append(c.Code, b)
+}
+
+func (c *Chunk) AddConstant(value Value) int {
+	switch value.(type) {
+	case float64, string:
+		// numbers and strings are interned, so that an identifier used many
+		// times in the same function takes a single slot
+		if c.indexes == nil {
+			c.indexes = make(map[Value]int)
+		}
+
+		if index, ok := c.indexes[value]; ok {
+			return index
+		}
+
+		c.indexes[value] = len(c.Constants)
+	}
+
+	c.Constants = append(c.Constants, value)
+
+	return len(c.Constants) - 1
+}
+
+// Span returns the span of the source code the byte at offset comes from.
+func (c *Chunk) Span(offset int) ast.Span {
+	i := sort.Search(len(c.spans), func(i int) bool {
+		return c.spans[i].offset > offset
+	})
+
+	if i == 0 {
+		return ast.Span{}
+	}
+
+	return c.spans[i-1].span
+}
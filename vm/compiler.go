@@ -0,0 +1,1003 @@
+//  MIT License
+//
+//  Copyright (c) 2019 Marco Pacini
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy
+//  of this software and associated documentation files (the "Software"), to deal
+//  in the Software without restriction, including without limitation the rights
+//  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//  copies of the Software, and to permit persons to whom the Software is
+//  furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all
+//  copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//  SOFTWARE.
+
+package vm
+
+import (
+	"fmt"
+	"github.com/marcopacini/go-lox/ast"
+	"math"
+)
+
+type functionType int
+
+const (
+	scriptType functionType = iota
+	functionKind
+	methodKind
+	initializerKind
+)
+
+type local struct {
+	name     string
+	depth    int
+	captured bool
+}
+
+type upvalue struct {
+	index   byte
+	isLocal bool
+}
+
+type scope struct {
+	enclosing *scope
+	function  *Function
+	kind      functionType
+	locals    []local
+	upvalues  []upvalue
+	depth     int
+	loop      *loop
+}
+
+// loop collects the jumps of the break and continue statements in a loop
+// body, patched once the loop is compiled.
+type loop struct {
+	enclosing *loop
+	depth     int
+	breaks    []int
+	continues []int
+}
+
+type classScope struct {
+	enclosing     *classScope
+	hasSuperclass bool
+}
+
+type Compiler struct {
+	*scope
+	class *classScope
+	// the code being compiled, where errors are reported
+	span ast.Span
+}
+
+// Compile translates the statements produced by the parser into the bytecode
+// of a top-level function, ready to be executed by a VM.
+func Compile(stmts []ast.Stmt) (*Function, error) {
+	c := Compiler{}
+	c.beginFunction("", scriptType)
+
+	for _, stmt := range stmts {
+		if err := c.statement(stmt); err != nil {
+			return nil, err
+		}
+	}
+
+	return c.endFunction(), nil
+}
+
+func (c *Compiler) beginFunction(name string, kind functionType) {
+	s := &scope{enclosing: c.scope, function: &Function{Name: name}, kind: kind}
+
+	// slot zero holds the receiver in methods and the callee otherwise
+	if kind == methodKind || kind == initializerKind {
+		s.locals = append(s.locals, local{"this", 0, false})
+	} else {
+		s.locals = append(s.locals, local{"", 0, false})
+	}
+
+	c.scope = s
+}
+
+func (c *Compiler) endFunction() *Function {
+	c.emitReturn()
+
+	f := c.function
+	f.UpvalueCount = len(c.upvalues)
+
+	c.scope = c.enclosing
+
+	return f
+}
+
+func (c *Compiler) errorf(format string, a ...interface{}) error {
+	return ast.Error{Span: c.span, Message: fmt.Sprintf(format, a...)}
+}
+
+func (c *Compiler) emit(bytes ...byte) {
+	for _, b := range bytes {
+		c.function.Chunk.Write(b, c.span)
+	}
+}
+
+func (c *Compiler) emitOp(op OpCode, operands ...byte) {
+	c.emit(byte(op))
+	c.emit(operands...)
+}
+
+func (c *Compiler) emitShort(op OpCode, operand int) {
+	c.emit(byte(op), byte(operand>>8), byte(operand))
+}
+
+func (c *Compiler) emitReturn() {
+	if c.kind == initializerKind {
+		c.emitOp(OpGetLocal, 0)
+	} else {
+		c.emitOp(OpNil)
+	}
+
+	c.emitOp(OpReturn)
+}
+
+func (c *Compiler) constant(value Value) (int, error) {
+	index := c.function.Chunk.AddConstant(value)
+	if index > math.MaxUint16 {
+		return 0, c.errorf("too many constants in one chunk")
+	}
+
+	return index, nil
+}
+
+func (c *Compiler) emitConstant(op OpCode, value Value) error {
+	index, err := c.constant(value)
+	if err != nil {
+		return err
+	}
+
+	c.emitShort(op, index)
+
+	return nil
+}
+
+func (c *Compiler) emitJump(op OpCode) int {
+	c.emit(byte(op), 0xff, 0xff)
+	return len(c.function.Code) - 2
+}
+
+func (c *Compiler) patchJump(offset int) error {
+	jump := len(c.function.Code) - offset - 2
+	if jump > math.MaxUint16 {
+		return c.errorf("too much code to jump over")
+	}
+
+	c.function.Code[offset] = byte(jump >> 8)
+	c.function.Code[offset+1] = byte(jump)
+
+	return nil
+}
+
+func (c *Compiler) emitLoop(start int) error {
+	offset := len(c.function.Code) - start + 3
+	if offset > math.MaxUint16 {
+		return c.errorf("loop body too large")
+	}
+
+	c.emitShort(OpLoop, offset)
+
+	return nil
+}
+
+func (c *Compiler) beginScope() {
+	c.depth++
+}
+
+func (c *Compiler) endScope() {
+	c.depth--
+
+	for len(c.locals) > 0 && c.locals[len(c.locals)-1].depth > c.depth {
+		if c.locals[len(c.locals)-1].captured {
+			c.emitOp(OpCloseUpvalue)
+		} else {
+			c.emitOp(OpPop)
+		}
+
+		c.locals = c.locals[:len(c.locals)-1]
+	}
+}
+
+func (c *Compiler) beginLoop() {
+	c.loop = &loop{enclosing: c.loop, depth: c.depth}
+}
+
+func (c *Compiler) endLoop() error {
+	for _, offset := range c.loop.breaks {
+		if err := c.patchJump(offset); err != nil {
+			return err
+		}
+	}
+
+	c.loop = c.loop.enclosing
+
+	return nil
+}
+
+// patchContinues makes the continue statements of the current loop jump to the
+// code emitted next.
+func (c *Compiler) patchContinues() error {
+	for _, offset := range c.loop.continues {
+		if err := c.patchJump(offset); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// jumpOut discards the locals declared in the loop body and emits a jump out
+// of it, returning the offset to patch.
+func (c *Compiler) jumpOut() int {
+	for j := len(c.locals) - 1; j >= 0 && c.locals[j].depth > c.loop.depth; j-- {
+		if c.locals[j].captured {
+			c.emitOp(OpCloseUpvalue)
+		} else {
+			c.emitOp(OpPop)
+		}
+	}
+
+	return c.emitJump(OpJump)
+}
+
+func (c *Compiler) addLocal(name string) error {
+	if len(c.locals) > math.MaxUint8 {
+		return c.errorf("too many local variables in function")
+	}
+
+	c.locals = append(c.locals, local{name, -1, false})
+
+	return nil
+}
+
+func (c *Compiler) markInitialized() {
+	if c.depth == 0 {
+		return
+	}
+
+	c.locals[len(c.locals)-1].depth = c.depth
+}
+
+// declare reserves a local slot for name, unless at global scope where
+// variables are late bound by name.
+func (c *Compiler) declare(name string) error {
+	if c.depth == 0 {
+		return nil
+	}
+
+	return c.addLocal(name)
+}
+
+func (c *Compiler) define(name string) error {
+	if c.depth > 0 {
+		c.markInitialized()
+		return nil
+	}
+
+	return c.emitConstant(OpDefineGlobal, name)
+}
+
+func resolveLocal(s *scope, name string) (int, bool, error) {
+	for i := len(s.locals) - 1; i >= 0; i-- {
+		if s.locals[i].name == name {
+			if s.locals[i].depth == -1 {
+				return 0, false, fmt.Errorf("cannot read local variable in its own initializer")
+			}
+
+			return i, true, nil
+		}
+	}
+
+	return 0, false, nil
+}
+
+func resolveUpvalue(s *scope, name string) (int, bool, error) {
+	if s.enclosing == nil {
+		return 0, false, nil
+	}
+
+	if index, ok, err := resolveLocal(s.enclosing, name); err != nil || ok {
+		if err != nil {
+			return 0, false, err
+		}
+
+		s.enclosing.locals[index].captured = true
+
+		return addUpvalue(s, byte(index), true)
+	}
+
+	if index, ok, err := resolveUpvalue(s.enclosing, name); err != nil || ok {
+		if err != nil {
+			return 0, false, err
+		}
+
+		return addUpvalue(s, byte(index), false)
+	}
+
+	return 0, false, nil
+}
+
+func addUpvalue(s *scope, index byte, isLocal bool) (int, bool, error) {
+	for i, u := range s.upvalues {
+		if u.index == index && u.isLocal == isLocal {
+			return i, true, nil
+		}
+	}
+
+	if len(s.upvalues) > math.MaxUint8 {
+		return 0, false, fmt.Errorf("too many closure variables in function")
+	}
+
+	s.upvalues = append(s.upvalues, upvalue{index, isLocal})
+
+	return len(s.upvalues) - 1, true, nil
+}
+
+func (c *Compiler) namedVariable(name string, assign ast.Expr) error {
+	var get, set OpCode
+	var arg int
+
+	if index, ok, err := resolveLocal(c.scope, name); err != nil {
+		return c.errorf("%v", err)
+	} else if ok {
+		get, set, arg = OpGetLocal, OpSetLocal, index
+	} else if index, ok, err := resolveUpvalue(c.scope, name); err != nil {
+		return c.errorf("%v", err)
+	} else if ok {
+		get, set, arg = OpGetUpvalue, OpSetUpvalue, index
+	} else {
+		get, set = OpGetGlobal, OpSetGlobal
+
+		if assign != nil {
+			if err := c.expression(assign); err != nil {
+				return err
+			}
+
+			return c.emitConstant(set, name)
+		}
+
+		return c.emitConstant(get, name)
+	}
+
+	if assign != nil {
+		if err := c.expression(assign); err != nil {
+			return err
+		}
+
+		c.emitOp(set, byte(arg))
+	} else {
+		c.emitOp(get, byte(arg))
+	}
+
+	return nil
+}
+
+func (c *Compiler) statement(stmt ast.Stmt) error {
+	switch s := stmt.(type) {
+	case ast.Block:
+		c.beginScope()
+		for _, stmt := range s.Stmts {
+			if err := c.statement(stmt); err != nil {
+				return err
+			}
+		}
+		c.endScope()
+
+	case ast.BreakStmt:
+		c.span = s.Keyword.Span()
+
+		if c.loop == nil {
+			return c.errorf("cannot use 'break' outside of a loop")
+		}
+
+		c.loop.breaks = append(c.loop.breaks, c.jumpOut())
+
+	case ast.ClassStmt:
+		return c.classDeclaration(s)
+
+	case ast.ContinueStmt:
+		c.span = s.Keyword.Span()
+
+		if c.loop == nil {
+			return c.errorf("cannot use 'continue' outside of a loop")
+		}
+
+		c.loop.continues = append(c.loop.continues, c.jumpOut())
+
+	case ast.Declaration:
+		c.span = s.Token.Span()
+
+		if err := c.declare(s.Lexeme); err != nil {
+			return err
+		}
+
+		if s.Expr != nil {
+			if err := c.expression(s.Expr); err != nil {
+				return err
+			}
+		} else {
+			c.emitOp(OpNil)
+		}
+
+		return c.define(s.Lexeme)
+
+	case ast.ExprStmt:
+		if err := c.expression(s.Expr); err != nil {
+			return err
+		}
+
+		c.emitOp(OpPop)
+
+	case ast.ForStmt:
+		return c.forStmt(s)
+
+	case ast.Function:
+		c.span = s.Name.Span()
+
+		if err := c.declare(s.Name.Lexeme); err != nil {
+			return err
+		}
+
+		c.markInitialized()
+
+		if err := c.functionBody(s, functionKind); err != nil {
+			return err
+		}
+
+		return c.define(s.Name.Lexeme)
+
+	case ast.IfStmt:
+		if err := c.expression(s.Condition); err != nil {
+			return err
+		}
+
+		thenJump := c.emitJump(OpJumpIfFalse)
+		c.emitOp(OpPop)
+
+		if err := c.statement(s.Then); err != nil {
+			return err
+		}
+
+		elseJump := c.emitJump(OpJump)
+
+		if err := c.patchJump(thenJump); err != nil {
+			return err
+		}
+
+		c.emitOp(OpPop)
+
+		if s.Else != nil {
+			if err := c.statement(s.Else); err != nil {
+				return err
+			}
+		}
+
+		return c.patchJump(elseJump)
+
+	case ast.PrintStmt:
+		if err := c.expression(s.Expr); err != nil {
+			return err
+		}
+
+		c.emitOp(OpPrint)
+
+	case ast.ReturnStmt:
+		c.span = s.Keyword.Span()
+
+		if s.Expr == nil {
+			c.emitReturn()
+			return nil
+		}
+
+		if c.kind == initializerKind {
+			return c.errorf("cannot return a value from an initializer")
+		}
+
+		if err := c.expression(s.Expr); err != nil {
+			return err
+		}
+
+		c.emitOp(OpReturn)
+
+	case ast.WhileStmt:
+		start := len(c.function.Code)
+
+		if err := c.expression(s.Condition); err != nil {
+			return err
+		}
+
+		exitJump := c.emitJump(OpJumpIfFalse)
+		c.emitOp(OpPop)
+
+		c.beginLoop()
+
+		if err := c.statement(s.Body); err != nil {
+			return err
+		}
+
+		if err := c.patchContinues(); err != nil {
+			return err
+		}
+
+		if err := c.emitLoop(start); err != nil {
+			return err
+		}
+
+		if err := c.patchJump(exitJump); err != nil {
+			return err
+		}
+
+		c.emitOp(OpPop)
+
+		return c.endLoop()
+
+	default:
+		c.span = ast.StmtSpan(stmt)
+		return c.errorf("unsupported statement %T", stmt)
+	}
+
+	return nil
+}
+
+func (c *Compiler) forStmt(s ast.ForStmt) error {
+	c.beginScope()
+
+	if s.Init != nil {
+		if err := c.statement(s.Init); err != nil {
+			return err
+		}
+	}
+
+	start := len(c.function.Code)
+	exitJump := -1
+
+	if s.Condition != nil {
+		if err := c.expression(s.Condition); err != nil {
+			return err
+		}
+
+		exitJump = c.emitJump(OpJumpIfFalse)
+		c.emitOp(OpPop)
+	}
+
+	c.beginLoop()
+
+	if err := c.statement(s.Body); err != nil {
+		return err
+	}
+
+	if err := c.patchContinues(); err != nil {
+		return err
+	}
+
+	if s.Increment != nil {
+		if err := c.expression(s.Increment); err != nil {
+			return err
+		}
+
+		c.emitOp(OpPop)
+	}
+
+	if err := c.emitLoop(start); err != nil {
+		return err
+	}
+
+	if exitJump != -1 {
+		if err := c.patchJump(exitJump); err != nil {
+			return err
+		}
+
+		c.emitOp(OpPop)
+	}
+
+	if err := c.endLoop(); err != nil {
+		return err
+	}
+
+	c.endScope()
+
+	return nil
+}
+
+func (c *Compiler) functionBody(f ast.Function, kind functionType) error {
+	name := f.Name.Lexeme
+	if f.Anonymous() {
+		name = "anonymous"
+	}
+
+	c.beginFunction(name, kind)
+	c.beginScope()
+
+	c.function.Arity = len(f.Arguments)
+	if c.function.Arity > math.MaxUint8 {
+		return c.errorf("cannot have more than %d parameters", math.MaxUint8)
+	}
+
+	for _, argument := range f.Arguments {
+		if err := c.declare(argument.Lexeme); err != nil {
+			return err
+		}
+
+		c.markInitialized()
+	}
+
+	for _, stmt := range f.Body {
+		if err := c.statement(stmt); err != nil {
+			return err
+		}
+	}
+
+	upvalues := c.upvalues
+	function := c.endFunction()
+
+	if err := c.emitConstant(OpClosure, function); err != nil {
+		return err
+	}
+
+	for _, u := range upvalues {
+		if u.isLocal {
+			c.emit(1, u.index)
+		} else {
+			c.emit(0, u.index)
+		}
+	}
+
+	return nil
+}
+
+func (c *Compiler) classDeclaration(s ast.ClassStmt) error {
+	c.span = s.Name.Span()
+	name := s.Name.Lexeme
+
+	if err := c.declare(name); err != nil {
+		return err
+	}
+
+	if err := c.emitConstant(OpClass, name); err != nil {
+		return err
+	}
+
+	if err := c.define(name); err != nil {
+		return err
+	}
+
+	c.class = &classScope{enclosing: c.class}
+	defer func() {
+		c.class = c.class.enclosing
+	}()
+
+	if s.Superclass != nil {
+		c.span = s.Superclass.Span
+
+		if s.Superclass.Lexeme == name {
+			return c.errorf("a class cannot inherit from itself")
+		}
+
+		if err := c.namedVariable(s.Superclass.Lexeme, nil); err != nil {
+			return err
+		}
+
+		c.beginScope()
+		if err := c.addLocal("super"); err != nil {
+			return err
+		}
+		c.markInitialized()
+
+		if err := c.namedVariable(name, nil); err != nil {
+			return err
+		}
+
+		c.span = s.Superclass.Span
+		c.emitOp(OpInherit)
+		c.class.hasSuperclass = true
+	}
+
+	if err := c.namedVariable(name, nil); err != nil {
+		return err
+	}
+
+	for _, method := range s.Methods {
+		c.span = method.Name.Span()
+
+		kind := methodKind
+		if method.Name.Lexeme == "init" {
+			kind = initializerKind
+		}
+
+		if err := c.functionBody(method, kind); err != nil {
+			return err
+		}
+
+		if err := c.emitConstant(OpMethod, method.Name.Lexeme); err != nil {
+			return err
+		}
+	}
+
+	c.emitOp(OpPop)
+
+	if c.class.hasSuperclass {
+		c.endScope()
+	}
+
+	return nil
+}
+
+func (c *Compiler) arguments(arguments []ast.Expr) (byte, error) {
+	if len(arguments) > math.MaxUint8 {
+		return 0, c.errorf("cannot have more than %d arguments", math.MaxUint8)
+	}
+
+	for _, argument := range arguments {
+		if err := c.expression(argument); err != nil {
+			return 0, err
+		}
+	}
+
+	return byte(len(arguments)), nil
+}
+
+func (c *Compiler) expression(expr ast.Expr) error {
+	switch e := expr.(type) {
+	case ast.Assign:
+		c.span = e.Variable.Token.Span()
+		return c.namedVariable(e.Variable.Lexeme, e.Expr)
+
+	case ast.Binary:
+		if err := c.expression(e.Left); err != nil {
+			return err
+		}
+
+		if err := c.expression(e.Right); err != nil {
+			return err
+		}
+
+		c.span = e.Operator.Span()
+
+		switch e.Operator.TokenType {
+		case ast.Plus:
+			c.emitOp(OpAdd)
+		case ast.Minus:
+			c.emitOp(OpSubtract)
+		case ast.Star:
+			c.emitOp(OpMultiply)
+		case ast.Slash:
+			c.emitOp(OpDivide)
+		case ast.Percent:
+			c.emitOp(OpModulo)
+		case ast.StarStar:
+			c.emitOp(OpPower)
+		case ast.EqualEqual:
+			c.emitOp(OpEqual)
+		case ast.NotEqual:
+			c.emitOp(OpEqual)
+			c.emitOp(OpNot)
+		case ast.Greater:
+			c.emitOp(OpGreater)
+		case ast.GreaterEqual:
+			c.emitOp(OpLess)
+			c.emitOp(OpNot)
+		case ast.Less:
+			c.emitOp(OpLess)
+		case ast.LessEqual:
+			c.emitOp(OpGreater)
+			c.emitOp(OpNot)
+		default:
+			return c.errorf("unsupported binary operator %s", e.Operator.Lexeme)
+		}
+
+	case ast.Call:
+		switch callee := e.Callee.(type) {
+		case ast.Get:
+			if err := c.expression(callee.Object); err != nil {
+				return err
+			}
+
+			count, err := c.arguments(e.Arguments)
+			if err != nil {
+				return err
+			}
+
+			c.span = callee.Name.Span()
+
+			index, err := c.constant(callee.Name.Lexeme)
+			if err != nil {
+				return err
+			}
+
+			c.emitShort(OpInvoke, index)
+			c.emit(count)
+
+		case ast.SuperExpr:
+			if err := c.checkSuper(callee); err != nil {
+				return err
+			}
+
+			if err := c.namedVariable("this", nil); err != nil {
+				return err
+			}
+
+			count, err := c.arguments(e.Arguments)
+			if err != nil {
+				return err
+			}
+
+			if err := c.namedVariable("super", nil); err != nil {
+				return err
+			}
+
+			index, err := c.constant(callee.Method.Lexeme)
+			if err != nil {
+				return err
+			}
+
+			c.emitShort(OpSuperInvoke, index)
+			c.emit(count)
+
+		default:
+			if err := c.expression(e.Callee); err != nil {
+				return err
+			}
+
+			count, err := c.arguments(e.Arguments)
+			if err != nil {
+				return err
+			}
+
+			c.span = e.Span
+			c.emitOp(OpCall, count)
+		}
+
+	case ast.Get:
+		if err := c.expression(e.Object); err != nil {
+			return err
+		}
+
+		c.span = e.Name.Span()
+
+		return c.emitConstant(OpGetProperty, e.Name.Lexeme)
+
+	case ast.Grouping:
+		return c.expression(e.Expr)
+
+	case ast.Lambda:
+		c.span = e.Function.Name.Span()
+		return c.functionBody(e.Function, functionKind)
+
+	case ast.Literal:
+		switch v := e.Value.(type) {
+		case nil:
+			c.emitOp(OpNil)
+		case bool:
+			if v {
+				c.emitOp(OpTrue)
+			} else {
+				c.emitOp(OpFalse)
+			}
+		default:
+			return c.emitConstant(OpConstant, v)
+		}
+
+	case ast.Logical:
+		if err := c.expression(e.Left); err != nil {
+			return err
+		}
+
+		if e.Operator.TokenType == ast.And {
+			endJump := c.emitJump(OpJumpIfFalse)
+			c.emitOp(OpPop)
+
+			if err := c.expression(e.Right); err != nil {
+				return err
+			}
+
+			return c.patchJump(endJump)
+		}
+
+		elseJump := c.emitJump(OpJumpIfFalse)
+		endJump := c.emitJump(OpJump)
+
+		if err := c.patchJump(elseJump); err != nil {
+			return err
+		}
+
+		c.emitOp(OpPop)
+
+		if err := c.expression(e.Right); err != nil {
+			return err
+		}
+
+		return c.patchJump(endJump)
+
+	case ast.Set:
+		if err := c.expression(e.Object); err != nil {
+			return err
+		}
+
+		if err := c.expression(e.Value); err != nil {
+			return err
+		}
+
+		c.span = e.Name.Span()
+
+		return c.emitConstant(OpSetProperty, e.Name.Lexeme)
+
+	case ast.SuperExpr:
+		if err := c.checkSuper(e); err != nil {
+			return err
+		}
+
+		if err := c.namedVariable("this", nil); err != nil {
+			return err
+		}
+
+		if err := c.namedVariable("super", nil); err != nil {
+			return err
+		}
+
+		return c.emitConstant(OpGetSuper, e.Method.Lexeme)
+
+	case ast.ThisExpr:
+		c.span = e.Keyword.Span()
+
+		if c.class == nil {
+			return c.errorf("cannot use 'this' outside of a class")
+		}
+
+		return c.namedVariable("this", nil)
+
+	case ast.Unary:
+		if err := c.expression(e.Right); err != nil {
+			return err
+		}
+
+		c.span = e.Operator.Span()
+
+		switch e.Operator.TokenType {
+		case ast.Not:
+			c.emitOp(OpNot)
+		case ast.Minus:
+			c.emitOp(OpNegate)
+		default:
+			return c.errorf("unsupported unary operator %s", e.Operator.Lexeme)
+		}
+
+	case ast.Variable:
+		c.span = e.Span
+		return c.namedVariable(e.Lexeme, nil)
+
+	default:
+		c.span = ast.ExprSpan(expr)
+		return c.errorf("unsupported expression %T", expr)
+	}
+
+	return nil
+}
+
+func (c *Compiler) checkSuper(s ast.SuperExpr) error {
+	c.span = s.Keyword.Span()
+
+	if c.class == nil {
+		return c.errorf("cannot use 'super' outside of a class")
+	}
+
+	if !c.class.hasSuperclass {
+		return c.errorf("cannot use 'super' in a class with no superclass")
+	}
+
+	return nil
+}
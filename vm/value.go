@@ -0,0 +1,104 @@
+//  MIT License
+//
+//  Copyright (c) 2019 Marco Pacini
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy
+//  of this software and associated documentation files (the "Software"), to deal
+//  in the Software without restriction, including without limitation the rights
+//  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//  copies of the Software, and to permit persons to whom the Software is
+//  furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all
+//  copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//  SOFTWARE.
+
+package vm
+
+import (
+	"fmt"
+	"github.com/marcopacini/go-lox/ast"
+)
+
+// Value is any value the virtual machine can store on its stack: nil, bool,
+// float64 and string for primitives, or one of the object types below.
+type Value interface{}
+
+type Function struct {
+	Name         string
+	Arity        int
+	UpvalueCount int
+	Chunk
+}
+
+func (f *Function) String() string {
+	if f.Name == "" {
+		return "<script>"
+	}
+
+	return fmt.Sprintf("<fn %s>", f.Name)
+}
+
+type Native struct {
+	Name  string
+	Arity int
+	Fn    func(arguments []Value) (Value, error)
+}
+
+func (n *Native) String() string {
+	return fmt.Sprintf("<native fn %s>", n.Name)
+}
+
+type Upvalue struct {
+	Location *Value
+	Closed   Value
+	slot     int
+	next     *Upvalue
+}
+
+type Closure struct {
+	*Function
+	Upvalues []*Upvalue
+}
+
+type Class struct {
+	Name    string
+	Methods map[string]*Closure
+}
+
+func (c *Class) String() string {
+	return c.Name
+}
+
+type Instance struct {
+	*Class
+	Fields map[string]Value
+}
+
+func (i *Instance) String() string {
+	return fmt.Sprintf("%s instance", i.Class.Name)
+}
+
+type BoundMethod struct {
+	Receiver Value
+	Method   *Closure
+}
+
+func (b *BoundMethod) String() string {
+	return b.Method.String()
+}
+
+func isFalsey(v Value) bool {
+	return !(ast.Literal{Value: v}).Bool()
+}
+
+func stringify(v Value) string {
+	return ast.Literal{Value: v}.String()
+}
@@ -0,0 +1,586 @@
+//  MIT License
+//
+//  Copyright (c) 2019 Marco Pacini
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy
+//  of this software and associated documentation files (the "Software"), to deal
+//  in the Software without restriction, including without limitation the rights
+//  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//  copies of the Software, and to permit persons to whom the Software is
+//  furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all
+//  copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//  SOFTWARE.
+
+package vm
+
+import (
+	"fmt"
+	"github.com/marcopacini/go-lox/ast"
+	"io"
+	"math"
+	"os"
+	"time"
+)
+
+const (
+	FramesMax = 256
+	StackMax  = FramesMax * 256
+)
+
+type frame struct {
+	closure *Closure
+	ip      int
+	slots   int
+}
+
+type VM struct {
+	Stdout io.Writer
+
+	frames       [FramesMax]frame
+	frameCount   int
+	stack        [StackMax]Value
+	top          int
+	globals      map[string]Value
+	openUpvalues *Upvalue
+}
+
+func New() *VM {
+	vm := &VM{Stdout: os.Stdout, globals: make(map[string]Value)}
+
+	vm.Define("clock", &Native{"clock", 0, func(arguments []Value) (Value, error) {
+		return float64(time.Now().UnixNano()) / float64(time.Second), nil
+	}})
+
+	return vm
+}
+
+func (vm *VM) Define(name string, value Value) {
+	vm.globals[name] = value
+}
+
+// Globals returns the global variables defined so far.
+func (vm *VM) Globals() map[string]Value {
+	return vm.globals
+}
+
+// Interpret runs the top-level function produced by Compile. Globals survive
+// across calls, the stack is reset on error.
+func (vm *VM) Interpret(f *Function) error {
+	closure := &Closure{f, nil}
+	vm.push(closure)
+
+	if err := vm.call(closure, 0); err != nil {
+		vm.reset()
+		return err
+	}
+
+	if err := vm.run(); err != nil {
+		vm.reset()
+		return err
+	}
+
+	return nil
+}
+
+func (vm *VM) reset() {
+	vm.top = 0
+	vm.frameCount = 0
+	vm.openUpvalues = nil
+}
+
+func (vm *VM) push(v Value) {
+	vm.stack[vm.top] = v
+	vm.top++
+}
+
+func (vm *VM) pop() Value {
+	vm.top--
+	return vm.stack[vm.top]
+}
+
+func (vm *VM) peek(distance int) Value {
+	return vm.stack[vm.top-1-distance]
+}
+
+// RuntimeError is an error raised while running compiled code.
+type RuntimeError struct {
+	ast.Span
+	Message string
+}
+
+func (e RuntimeError) Error() string {
+	return ast.Error{Span: e.Span, Message: e.Message}.Error()
+}
+
+// Render returns the error followed by the code raising it, as
+// ast.Error.Render does.
+func (e RuntimeError) Render(source string) string {
+	return ast.Error{Span: e.Span, Message: e.Message}.Render(source)
+}
+
+func (vm *VM) errorf(format string, a ...interface{}) error {
+	f := &vm.frames[vm.frameCount-1]
+	span := f.closure.Chunk.Span(f.ip - 1)
+
+	return RuntimeError{span, fmt.Sprintf(format, a...)}
+}
+
+func (vm *VM) call(closure *Closure, count int) error {
+	if count != closure.Arity {
+		return vm.errorf("expected %d arguments but got %d", closure.Arity, count)
+	}
+
+	if vm.frameCount == FramesMax {
+		return vm.errorf("stack overflow")
+	}
+
+	vm.frames[vm.frameCount] = frame{closure, 0, vm.top - count - 1}
+	vm.frameCount++
+
+	return nil
+}
+
+func (vm *VM) callValue(callee Value, count int) error {
+	switch c := callee.(type) {
+	case *BoundMethod:
+		vm.stack[vm.top-count-1] = c.Receiver
+		return vm.call(c.Method, count)
+
+	case *Class:
+		vm.stack[vm.top-count-1] = &Instance{c, make(map[string]Value)}
+
+		if initializer, ok := c.Methods["init"]; ok {
+			return vm.call(initializer, count)
+		}
+
+		if count != 0 {
+			return vm.errorf("expected 0 arguments but got %d", count)
+		}
+
+		return nil
+
+	case *Closure:
+		return vm.call(c, count)
+
+	case *Native:
+		if count != c.Arity {
+			return vm.errorf("expected %d arguments but got %d", c.Arity, count)
+		}
+
+		result, err := c.Fn(vm.stack[vm.top-count : vm.top])
+		if err != nil {
+			return vm.errorf("%v", err)
+		}
+
+		vm.top -= count + 1
+		vm.push(result)
+
+		return nil
+	}
+
+	return vm.errorf("can only call functions and classes")
+}
+
+func (vm *VM) invokeFromClass(class *Class, name string, count int) error {
+	method, ok := class.Methods[name]
+	if !ok {
+		return vm.errorf("undefined property '%s'", name)
+	}
+
+	return vm.call(method, count)
+}
+
+func (vm *VM) invoke(name string, count int) error {
+	instance, ok := vm.peek(count).(*Instance)
+	if !ok {
+		return vm.errorf("only instances have methods")
+	}
+
+	if value, ok := instance.Fields[name]; ok {
+		vm.stack[vm.top-count-1] = value
+		return vm.callValue(value, count)
+	}
+
+	return vm.invokeFromClass(instance.Class, name, count)
+}
+
+func (vm *VM) bindMethod(class *Class, name string) error {
+	method, ok := class.Methods[name]
+	if !ok {
+		return vm.errorf("undefined property '%s'", name)
+	}
+
+	bound := &BoundMethod{vm.peek(0), method}
+	vm.pop()
+	vm.push(bound)
+
+	return nil
+}
+
+func (vm *VM) captureUpvalue(slot int) *Upvalue {
+	var previous *Upvalue
+
+	u := vm.openUpvalues
+	for u != nil && u.slot > slot {
+		previous = u
+		u = u.next
+	}
+
+	if u != nil && u.slot == slot {
+		return u
+	}
+
+	created := &Upvalue{Location: &vm.stack[slot], slot: slot, next: u}
+
+	if previous == nil {
+		vm.openUpvalues = created
+	} else {
+		previous.next = created
+	}
+
+	return created
+}
+
+func (vm *VM) closeUpvalues(last int) {
+	for vm.openUpvalues != nil && vm.openUpvalues.slot >= last {
+		u := vm.openUpvalues
+		u.Closed = *u.Location
+		u.Location = &u.Closed
+		vm.openUpvalues = u.next
+	}
+}
+
+func (vm *VM) run() error {
+	f := &vm.frames[vm.frameCount-1]
+	code := f.closure.Code
+
+	readByte := func() byte {
+		f.ip++
+		return code[f.ip-1]
+	}
+
+	readShort := func() int {
+		f.ip += 2
+		return int(code[f.ip-2])<<8 | int(code[f.ip-1])
+	}
+
+	readConstant := func() Value {
+		return f.closure.Constants[readShort()]
+	}
+
+	readString := func() string {
+		return readConstant().(string)
+	}
+
+	numbers := func(op string) (float64, float64, error) {
+		l, lok := vm.peek(1).(float64)
+		r, rok := vm.peek(0).(float64)
+
+		if !lok || !rok {
+			return 0, 0, vm.errorf("invalid operands for binary %s: %T, %T", op, vm.peek(1), vm.peek(0))
+		}
+
+		vm.top -= 2
+
+		return l, r, nil
+	}
+
+	for {
+		switch OpCode(readByte()) {
+		case OpConstant:
+			vm.push(readConstant())
+
+		case OpNil:
+			vm.push(nil)
+
+		case OpTrue:
+			vm.push(true)
+
+		case OpFalse:
+			vm.push(false)
+
+		case OpPop:
+			vm.top--
+
+		case OpGetLocal:
+			vm.push(vm.stack[f.slots+int(readByte())])
+
+		case OpSetLocal:
+			vm.stack[f.slots+int(readByte())] = vm.peek(0)
+
+		case OpGetGlobal:
+			name := readString()
+
+			value, ok := vm.globals[name]
+			if !ok {
+				return vm.errorf("undefined variable %v", name)
+			}
+
+			vm.push(value)
+
+		case OpDefineGlobal:
+			vm.globals[readString()] = vm.pop()
+
+		case OpSetGlobal:
+			name := readString()
+
+			if _, ok := vm.globals[name]; !ok {
+				return vm.errorf("undefined variable %v", name)
+			}
+
+			vm.globals[name] = vm.peek(0)
+
+		case OpGetUpvalue:
+			vm.push(*f.closure.Upvalues[readByte()].Location)
+
+		case OpSetUpvalue:
+			*f.closure.Upvalues[readByte()].Location = vm.peek(0)
+
+		case OpGetProperty:
+			name := readString()
+
+			instance, ok := vm.peek(0).(*Instance)
+			if !ok {
+				return vm.errorf("invalid property: %v", name)
+			}
+
+			if value, ok := instance.Fields[name]; ok {
+				vm.top--
+				vm.push(value)
+				break
+			}
+
+			if err := vm.bindMethod(instance.Class, name); err != nil {
+				return err
+			}
+
+		case OpSetProperty:
+			name := readString()
+
+			instance, ok := vm.peek(1).(*Instance)
+			if !ok {
+				return vm.errorf("only instances have fields")
+			}
+
+			instance.Fields[name] = vm.peek(0)
+
+			value := vm.pop()
+			vm.top--
+			vm.push(value)
+
+		case OpGetSuper:
+			name := readString()
+			superclass := vm.pop().(*Class)
+
+			if err := vm.bindMethod(superclass, name); err != nil {
+				return err
+			}
+
+		case OpEqual:
+			r := vm.pop()
+			l := vm.pop()
+			vm.push(l == r)
+
+		case OpGreater:
+			l, r, err := numbers(">")
+			if err != nil {
+				return err
+			}
+
+			vm.push(l > r)
+
+		case OpLess:
+			l, r, err := numbers("<")
+			if err != nil {
+				return err
+			}
+
+			vm.push(l < r)
+
+		case OpAdd:
+			if l, ok := vm.peek(1).(string); ok {
+				if r, ok := vm.peek(0).(string); ok {
+					vm.top -= 2
+					vm.push(l + r)
+					break
+				}
+			}
+
+			l, r, err := numbers("+")
+			if err != nil {
+				return err
+			}
+
+			vm.push(l + r)
+
+		case OpSubtract:
+			l, r, err := numbers("-")
+			if err != nil {
+				return err
+			}
+
+			vm.push(l - r)
+
+		case OpMultiply:
+			l, r, err := numbers("*")
+			if err != nil {
+				return err
+			}
+
+			vm.push(l * r)
+
+		case OpDivide:
+			l, r, err := numbers("/")
+			if err != nil {
+				return err
+			}
+
+			vm.push(l / r)
+
+		case OpModulo:
+			l, r, err := numbers("%")
+			if err != nil {
+				return err
+			}
+
+			vm.push(math.Mod(l, r))
+
+		case OpPower:
+			l, r, err := numbers("**")
+			if err != nil {
+				return err
+			}
+
+			vm.push(math.Pow(l, r))
+
+		case OpNot:
+			vm.push(isFalsey(vm.pop()))
+
+		case OpNegate:
+			n, ok := vm.peek(0).(float64)
+			if !ok {
+				return vm.errorf("bad operand for unary -: %T", vm.peek(0))
+			}
+
+			vm.stack[vm.top-1] = -n
+
+		case OpPrint:
+			fmt.Fprintln(vm.Stdout, stringify(vm.pop()))
+
+		case OpJump:
+			offset := readShort()
+			f.ip += offset
+
+		case OpJumpIfFalse:
+			offset := readShort()
+			if isFalsey(vm.peek(0)) {
+				f.ip += offset
+			}
+
+		case OpLoop:
+			offset := readShort()
+			f.ip -= offset
+
+		case OpCall:
+			count := int(readByte())
+
+			if err := vm.callValue(vm.peek(count), count); err != nil {
+				return err
+			}
+
+			f = &vm.frames[vm.frameCount-1]
+			code = f.closure.Code
+
+		case OpInvoke:
+			name := readString()
+			count := int(readByte())
+
+			if err := vm.invoke(name, count); err != nil {
+				return err
+			}
+
+			f = &vm.frames[vm.frameCount-1]
+			code = f.closure.Code
+
+		case OpSuperInvoke:
+			name := readString()
+			count := int(readByte())
+			superclass := vm.pop().(*Class)
+
+			if err := vm.invokeFromClass(superclass, name, count); err != nil {
+				return err
+			}
+
+			f = &vm.frames[vm.frameCount-1]
+			code = f.closure.Code
+
+		case OpClosure:
+			function := readConstant().(*Function)
+			closure := &Closure{function, make([]*Upvalue, function.UpvalueCount)}
+
+			for i := range closure.Upvalues {
+				isLocal := readByte()
+				index := int(readByte())
+
+				if isLocal == 1 {
+					closure.Upvalues[i] = vm.captureUpvalue(f.slots + index)
+				} else {
+					closure.Upvalues[i] = f.closure.Upvalues[index]
+				}
+			}
+
+			vm.push(closure)
+
+		case OpCloseUpvalue:
+			vm.closeUpvalues(vm.top - 1)
+			vm.top--
+
+		case OpReturn:
+			result := vm.pop()
+			vm.closeUpvalues(f.slots)
+
+			vm.frameCount--
+			if vm.frameCount == 0 {
+				vm.top = 0
+				return nil
+			}
+
+			vm.top = f.slots
+			vm.push(result)
+
+			f = &vm.frames[vm.frameCount-1]
+			code = f.closure.Code
+
+		case OpClass:
+			vm.push(&Class{readString(), make(map[string]*Closure)})
+
+		case OpInherit:
+			superclass, ok := vm.peek(1).(*Class)
+			if !ok {
+				return vm.errorf("superclass must be a class")
+			}
+
+			subclass := vm.peek(0).(*Class)
+			for name, method := range superclass.Methods {
+				subclass.Methods[name] = method
+			}
+
+			vm.top--
+
+		case OpMethod:
+			method := vm.peek(0).(*Closure)
+			class := vm.peek(1).(*Class)
+			class.Methods[readString()] = method
+			vm.top--
+		}
+	}
+}
@@ -0,0 +1,143 @@
+//  MIT License
+//
+//  Copyright (c) 2019 Marco Pacini
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy
+//  of this software and associated documentation files (the "Software"), to deal
+//  in the Software without restriction, including without limitation the rights
+//  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//  copies of the Software, and to permit persons to whom the Software is
+//  furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all
+//  copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//  SOFTWARE.
+
+package vm
+
+import (
+	"bytes"
+	"github.com/marcopacini/go-lox/ast"
+	"strings"
+	"testing"
+)
+
+func TestVM_Interpret(t *testing.T) {
+	table := []struct {
+		in  string
+		out []string
+	}{
+		{"print 1 + 2 * 3;", []string{"7"}},
+		{"print \"a\" + \"b\";", []string{"ab"}},
+		{"print !nil; print 1 == 1; print 2 >= 3;", []string{"true", "true", "false"}},
+		{"print 7 % 3; print -2 ** 2; print 2 ** 3 ** 2;", []string{"1", "-4", "512"}},
+		{"var a = 1; { var a = 2; print a; } print a;", []string{"2", "1"}},
+		{"for (var i = 0; i < 3; i = i + 1) print i;", []string{"0", "1", "2"}},
+		{"fun f(n) { if (n < 2) return n; return f(n - 1) + f(n - 2); } print f(10);", []string{"55"}},
+		{"fun c() { var i = 0; fun g() { i = i + 1; return i; } return g; } var g = c(); g(); print g();", []string{"2"}},
+		{"class A { init(x) { this.x = x; } get() { return this.x; } } print A(3).get();", []string{"3"}},
+		{"class A { hi() { return \"A\"; } } class B < A { hi() { return super.hi() + \"B\"; } } print B().hi();", []string{"AB"}},
+		{"class A { m() { return this; } } var a = A(); print a.m() == a;", []string{"true"}},
+		{"for (var i = 0; i < 5; i = i + 1) { var j = i; if (j == 1) continue; if (j == 3) break; print j; }", []string{"0", "2"}},
+		{"var i = 0; while (true) { i = i + 1; { var k = i; if (k < 3) continue; } break; } print i;", []string{"3"}},
+		{"var n = 2; var f = fun (x) => x * n; print f(3); print fun () {};", []string{"6", "<fn anonymous>"}},
+	}
+
+	for _, test := range table {
+		t.Run(test.in, func(t *testing.T) {
+			scanner := ast.Scanner{Text: test.in}
+			tokens, err := scanner.Scan()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			parser := ast.Parser{Tokens: tokens}
+			stmts, err := parser.Parse()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			f, err := Compile(stmts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var out bytes.Buffer
+
+			vm := New()
+			vm.Stdout = &out
+
+			if err := vm.Interpret(f); err != nil {
+				t.Fatal(err)
+			}
+
+			if want := strings.Join(test.out, "\n") + "\n"; out.String() != want {
+				t.Errorf("want %q, got %q", want, out.String())
+			}
+		})
+	}
+}
+
+func TestVM_RuntimeError(t *testing.T) {
+	table := []struct {
+		in  string
+		err string
+	}{
+		{"print -\"a\";", "error at line 1, column 7: bad operand for unary -: string"},
+		{"print x;", "error at line 1, column 7: undefined variable x"},
+		{"fun f(a) {}\nf();", "error at line 2, column 1: expected 1 arguments but got 0"},
+		{"var a = \"x\";\nclass B < a {}", "error at line 2, column 11: superclass must be a class"},
+	}
+
+	for _, test := range table {
+		t.Run(test.in, func(t *testing.T) {
+			scanner := ast.Scanner{Text: test.in}
+			tokens, _ := scanner.Scan()
+
+			parser := ast.Parser{Tokens: tokens}
+			stmts, _ := parser.Parse()
+
+			f, err := Compile(stmts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := New().Interpret(f); err == nil || err.Error() != test.err {
+				t.Errorf("want %q, got %v", test.err, err)
+			}
+		})
+	}
+}
+
+func TestCompile_Error(t *testing.T) {
+	table := []struct {
+		in  string
+		err string
+	}{
+		{"print 1;\ntry {} catch (e) {}", "error at line 2, column 1: unsupported statement ast.TryStmt"},
+		{"var a;\nvar l = [a];", "error at line 2, column 9: unsupported expression ast.ListExpr"},
+		{"while (true) {}\nbreak;", "error at line 2, column 1: cannot use 'break' outside of a loop"},
+		{"class A < A {}", "error at line 1, column 11: a class cannot inherit from itself"},
+	}
+
+	for _, test := range table {
+		t.Run(test.in, func(t *testing.T) {
+			scanner := ast.Scanner{Text: test.in}
+			tokens, _ := scanner.Scan()
+
+			parser := ast.Parser{Tokens: tokens}
+			stmts, _ := parser.Parse()
+
+			if _, err := Compile(stmts); err == nil || err.Error() != test.err {
+				t.Errorf("want %q, got %v", test.err, err)
+			}
+		})
+	}
+}